@@ -1,24 +1,61 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/softwarespot/chatterbox/pkg/room"
 	"github.com/softwarespot/chatterbox/pkg/socket"
 	"golang.org/x/net/websocket"
 )
 
+// Number of the most recent messages replayed to a client when joining a room
+const historyReplaySize = 50
+
+// Maximum duration to leave the room when disconnecting, as the socket's context is cancelled by then
+const leaveRoomTimeout = 5 * time.Second
+
+// Key of the room the socket has joined
+var currRoomKey = socket.NewDataKey[*room.Room[socket.Args]]("room")
+
 type ChatServer struct {
-	server *websocket.Server
-	rm     *room.Manager[socket.Args]
+	server    *websocket.Server
+	rm        *room.Manager[socket.Args]
+	logger    *slog.Logger
+	roomCfg   *room.Config[socket.Args]
+	socketCfg *socket.Config
 }
 
-func NewChatServer() *ChatServer {
+// NewChatServer initializes a chat server, where the room configuration is used as the base configuration of
+// each room and the socket configuration for all sockets. When a configuration is nil, then the default configuration is used
+func NewChatServer(logger *slog.Logger, roomCfg *room.Config[socket.Args], socketCfg *socket.Config) *ChatServer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if socketCfg == nil {
+		socketCfg = socket.NewSocketConfig()
+	}
+	if roomCfg == nil {
+		roomCfg = room.NewRoomConfig[socket.Args]()
+	}
+	if roomCfg.Topics == nil {
+		roomCfg.Topics = messageTopics
+	}
+	if roomCfg.HistoryFilter == nil {
+		roomCfg.HistoryFilter = isChatMessage
+	}
+
 	cs := &ChatServer{
-		server: nil,
-		rm:     room.NewManager[socket.Args](),
+		server:    nil,
+		rm:        room.NewManager(roomCfg),
+		logger:    logger,
+		roomCfg:   roomCfg,
+		socketCfg: socketCfg,
 	}
 
 	cs.server = &websocket.Server{
@@ -36,50 +73,59 @@ func NewChatServer() *ChatServer {
 }
 
 func (cs *ChatServer) ServeChat(conn *websocket.Conn) {
-	r := conn.Request()
-	log.Printf("connection established for %s", r.RemoteAddr)
-	defer log.Printf("connection disconnected for %s", r.RemoteAddr)
+	connLogger := cs.logger.With(slog.String("remote_addr", conn.Request().RemoteAddr))
+	connLogger.Info("connection established")
+	defer connLogger.Info("connection disconnected")
 
-	err := socket.IO(conn, func(s *socket.Socket) error {
+	err := socket.IO(conn, cs.socketCfg, func(s *socket.Socket) error {
+		logger := s.Logger()
 		c := s.Client()
-		var currRoom *room.Room[socket.Args]
 
-		leaveRoomFn := func() {
-			if currRoom == nil {
+		leaveRoomFn := func(ctx context.Context) {
+			currRoom, ok := socket.GetData(s, currRoomKey)
+			if !ok {
 				return
 			}
 
-			currRoom.Unregister(c)
+			if err := currRoom.UnregisterContext(ctx, c); err != nil {
+				logger.Warn("unable to leave the room", slog.String("room", currRoom.Name()), slog.Any("error", err))
+			}
 
-			c.Send(socket.Args{
+			c.SendContext(ctx, socket.Args{
 				"System",
 				fmt.Sprintf("Left the room %s.", currRoom.Name()),
 			})
-			currRoom.Send(c, socket.Args{
+			currRoom.SendContext(ctx, c, socket.Args{
 				"System",
 				fmt.Sprintf("Socket ID %s left the room %s.", c.ID(), currRoom.Name()),
 			})
 
-			log.Printf("socket ID %s left the room %s", c.ID(), currRoom.Name())
+			logger.Info("left the room", slog.String("room", currRoom.Name()))
 
-			currRoom = nil
+			socket.DeleteData(s, currRoomKey)
 		}
 
 		s.On("connect", func(_ ...any) {
-			log.Printf("socket ID %s opened the connection", c.ID())
+			logger.Info("opened the connection")
+
+			cs.rm.AddClient(c)
 
 			go func() {
 				for m := range c.Messages() {
 					if err := s.Emit("message", m...); err != nil {
-						log.Printf("error sending message to socket ID %s: %v", c.ID(), err)
+						logger.Error("unable to send the message", slog.String("event", "message"), slog.Any("error", err))
 						break
 					}
 				}
 			}()
 		}).On("disconnect", func(_ ...any) {
-			log.Printf("socket ID %s closed the connection", c.ID())
+			logger.Info("closed the connection")
+
+			ctx, cancel := context.WithTimeout(context.Background(), leaveRoomTimeout)
+			defer cancel()
 
-			leaveRoomFn()
+			leaveRoomFn(ctx)
+			cs.rm.RemoveClient(c)
 		})
 
 		s.On("ping", func(args ...any) {
@@ -88,49 +134,74 @@ func (cs *ChatServer) ServeChat(conn *websocket.Conn) {
 			}
 		})
 
-		s.On("join", func(args ...any) {
-			leaveRoomFn()
-
+		s.OnContext("join", func(ctx context.Context, args ...any) {
 			roomName, err := socket.ArgAt[string](args, 0)
 			if err != nil {
-				log.Printf("socket ID %s encountered error: %v", c.ID(), err)
+				logger.Warn("invalid room name", slog.String("event", "join"), slog.Any("error", err))
 				return
 			}
 
-			currRoom = cs.rm.Load(roomName, nil)
-			log.Printf("socket ID %s loaded the room %s", c.ID(), currRoom.Name())
+			// The password is optional
+			password, _ := socket.ArgAt[string](args, 1)
 
-			currRoom.Register(c)
+			ackFn, hasAckFn := socket.GetAckFunc(args)
+
+			// The password is only used when the room is created i.e. the first client to join sets the password
+			joinRoom := cs.rm.Load(roomName, cs.newRoomConfig(password))
+			logger.Debug("loaded the room", slog.String("room", joinRoom.Name()))
+
+			// The context is cancelled when disconnecting, so a room which is slow to respond doesn't block disconnecting
+			if err := joinRoom.RegisterWithPasswordContext(ctx, c, password); err != nil {
+				logger.Warn("unable to join the room", slog.String("room", joinRoom.Name()), slog.Any("error", err))
+				if hasAckFn {
+					ackFn(s.AckError(err)...)
+				}
+				return
+			}
+
+			// Leave the current room once registered in the room, so the client stays in the current room
+			// when unable to join
+			if currRoom, ok := socket.GetData(s, currRoomKey); ok && currRoom != joinRoom {
+				leaveRoomFn(ctx)
+			}
+			socket.SetData(s, currRoomKey, joinRoom)
 
 			c.Send(socket.Args{
 				"System",
-				fmt.Sprintf("Joined the room %s. Currently there are %d client(s).", currRoom.Name(), currRoom.Size()-1),
+				fmt.Sprintf("Joined the room %s. Currently there are %d client(s).", joinRoom.Name(), joinRoom.Size()-1),
 			})
-			currRoom.Send(c, socket.Args{
+			for _, msg := range joinRoom.History(room.HistoryQuery{Limit: historyReplaySize}) {
+				text, _ := socket.ArgAt[string](msg.Data, 1)
+				c.Send(socket.Args{
+					"History",
+					fmt.Sprintf("%s: %s", msg.SenderID, text),
+				})
+			}
+			joinRoom.Send(c, socket.Args{
 				"System",
-				fmt.Sprintf("Socket ID %s joined the room %s.", c.ID(), currRoom.Name()),
+				fmt.Sprintf("Socket ID %s joined the room %s.", c.ID(), joinRoom.Name()),
 			})
 
-			ackFn, ok := socket.GetAckFunc(args)
-			if ok {
+			if hasAckFn {
 				ackFn()
 			}
 
-			log.Printf("socket ID %s joined the room %s", c.ID(), currRoom.Name())
+			logger.Info("joined the room", slog.String("room", joinRoom.Name()))
 		})
 
-		s.On("leave", func(_ ...any) {
-			leaveRoomFn()
+		s.OnContext("leave", func(ctx context.Context, _ ...any) {
+			leaveRoomFn(ctx)
 		})
 
-		s.On("message", func(args ...any) {
-			if currRoom == nil {
+		s.OnContext("message", func(ctx context.Context, args ...any) {
+			currRoom, ok := socket.GetData(s, currRoomKey)
+			if !ok {
 				return
 			}
 
 			msg, err := socket.ArgAt[string](args, 0)
 			if err != nil {
-				log.Printf("socket ID %s encountered error: %v", c.ID(), err)
+				logger.Warn("invalid message", slog.String("event", "message"), slog.Any("error", err))
 				return
 			}
 
@@ -138,17 +209,162 @@ func (cs *ChatServer) ServeChat(conn *websocket.Conn) {
 				"Sender",
 				msg,
 			})
-			currRoom.Send(c, socket.Args{
+			currRoom.SendContext(ctx, c, socket.Args{
 				"Receiver",
 				msg,
 			})
-			log.Printf("socket ID %s broadcast message %q to the room %s", c.ID(), msg, currRoom.Name())
+			logger.Debug("broadcast the message", slog.String("room", currRoom.Name()), slog.String("event", "message"))
+		})
+
+		s.On("history", func(args ...any) {
+			ackFn, ok := socket.GetAckFunc(args)
+			if !ok {
+				return
+			}
+			currRoom, ok := socket.GetData(s, currRoomKey)
+			if !ok {
+				ackFn([]any{})
+				return
+			}
+
+			q := room.HistoryQuery{
+				Limit: historyReplaySize,
+			}
+			if opts, err := socket.ArgAt[map[string]any](args, 0); err == nil {
+				if beforeID, ok := opts["beforeId"].(string); ok {
+					q.BeforeID = beforeID
+				}
+				if before, ok := opts["before"].(float64); ok {
+					q.Before = time.UnixMilli(int64(before))
+				}
+				if limit, ok := opts["limit"].(float64); ok && limit > 0 {
+					q.Limit = min(int(limit), historyReplaySize)
+				}
+			}
+			msgs := currRoom.History(q)
+			if msgs == nil {
+				msgs = []room.Message[socket.Args]{}
+			}
+			ackFn(msgs)
+		})
+
+		s.OnContext("subscribe", func(ctx context.Context, args ...any) {
+			ackFn, hasAckFn := socket.GetAckFunc(args)
+			currRoom, ok := socket.GetData(s, currRoomKey)
+			if !ok {
+				if hasAckFn {
+					ackFn(s.AckError(room.ErrRoomClientNotRegistered)...)
+				}
+				return
+			}
+
+			// Topics are either hashtags e.g. "#alerts" or mentions e.g. "@<socket ID>".
+			// When no topics are defined, then all messages are received
+			var topics []string
+			for i := range args {
+				if topic, err := socket.ArgAt[string](args, i); err == nil {
+					topics = append(topics, topic)
+				}
+			}
+
+			err := currRoom.SubscribeContext(ctx, c, topics...)
+			if err != nil {
+				logger.Warn("unable to subscribe to the topics", slog.String("room", currRoom.Name()), slog.Any("topics", topics), slog.Any("error", err))
+			} else {
+				logger.Info("subscribed to the topics", slog.String("room", currRoom.Name()), slog.Any("topics", topics))
+			}
+			if hasAckFn {
+				if err != nil {
+					ackFn(s.AckError(err)...)
+				} else {
+					ackFn()
+				}
+			}
+		})
+
+		s.On("private", func(args ...any) {
+			toID, err := socket.ArgAt[string](args, 0)
+			if err != nil {
+				logger.Warn("invalid private message socket ID", slog.String("event", "private"), slog.Any("error", err))
+				return
+			}
+
+			msg, err := socket.ArgAt[string](args, 1)
+			if err != nil {
+				logger.Warn("invalid private message", slog.String("event", "private"), slog.Any("error", err))
+				return
+			}
+
+			err = cs.rm.SendTo(toID, socket.Args{
+				"Private",
+				fmt.Sprintf("%s: %s", c.ID(), msg),
+			})
+			if ackFn, ok := socket.GetAckFunc(args); ok {
+				if err != nil {
+					ackFn(s.AckError(err)...)
+				} else {
+					ackFn()
+				}
+			}
+			if err != nil {
+				logger.Warn("unable to send the private message", slog.String("event", "private"), slog.String("to_socket_id", toID), slog.Any("error", err))
+				return
+			}
+			logger.Debug("sent the private message", slog.String("event", "private"), slog.String("to_socket_id", toID))
+		})
+
+		s.OnUnknown(func(event string, args socket.Args) {
+			logger.Debug("received an unknown event", slog.String("event", event))
+			if ackFn, ok := socket.GetAckFunc(args); ok {
+				ackFn(s.AckError(fmt.Errorf("unknown event %q", event))...)
+			}
 		})
 
 		return nil
 	})
 
-	log.Printf("socket completed: %v", err)
+	connLogger.Info("socket completed", slog.Any("error", err))
+}
+
+// newRoomConfig returns the configuration of a room created by a client, where the room is protected by
+// the password when not empty
+func (cs *ChatServer) newRoomConfig(password string) *room.Config[socket.Args] {
+	cfg := *cs.roomCfg
+	if password != "" {
+		cfg.Password = password
+	}
+	return &cfg
+}
+
+// messageTopics returns the hashtags and mentions of a chat message e.g. "#alerts" or "@<socket ID>"
+func messageTopics(args socket.Args) []string {
+	msg, err := socket.ArgAt[string](args, 1)
+	if err != nil {
+		return nil
+	}
+
+	var topics []string
+	for _, word := range strings.Fields(msg) {
+		if len(word) > 1 && (word[0] == '#' || word[0] == '@') {
+			topics = append(topics, word)
+		}
+	}
+	return topics
+}
+
+// isChatMessage returns true when the message was sent by a client, where the "System" notices e.g. joined or
+// left the room are not kept in the history
+func isChatMessage(args socket.Args) bool {
+	sender, err := socket.ArgAt[string](args, 0)
+	return err == nil && sender != "System"
+}
+
+// ServeStats responds with the statistics of the rooms as JSON
+func (cs *ChatServer) ServeStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(cs.rm.Stats()); err != nil {
+		cs.logger.Error("unable to encode the stats", slog.Any("error", err))
+	}
 }
 
 func (cs *ChatServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -1,10 +1,125 @@
 package main
 
 import (
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/softwarespot/chatterbox/pkg/broker"
+	"github.com/softwarespot/chatterbox/pkg/metrics"
+	"github.com/softwarespot/chatterbox/pkg/room"
+	"github.com/softwarespot/chatterbox/pkg/socket"
+	"github.com/softwarespot/chatterbox/pkg/store"
+	"github.com/softwarespot/chatterbox/pkg/trace"
 )
 
+var errInvalidAuthToken = errors.New("invalid auth token")
+
 func main() {
+	addr := flag.String("addr", ":10000", "address to listen on")
+	adminAddr := flag.String("admin-addr", "127.0.0.1:10001", "address to listen on for the admin endpoints i.e. /stats, /metrics and /debug/events. When empty, the admin endpoints are disabled")
+	brokerAddr := flag.String("broker", "", "address of the TCP broker used to fan out room messages to other nodes")
+	brokerServeAddr := flag.String("broker-serve", "", "address to run a stand-in TCP broker on for the other nodes")
+	roomMaxClients := flag.Int("room-max-clients", 0, "maximum number of clients allowed in a room. When zero, rooms are unlimited")
+	storePath := flag.String("store", "", "path of the file used to persist the room messages. When empty, messages are not persisted")
+	logLevel := flag.String("log-level", "info", "minimum level of the logs i.e. debug, info, warn or error")
+	logFormat := flag.String("log-format", "json", "format of the logs i.e. json or text")
+	rateLimit := flag.Float64("rate-limit", 0, "number of events allowed per second for a socket. When zero, events are unlimited")
+	ipRateLimit := flag.Float64("ip-rate-limit", 0, "number of events allowed per second across the sockets of a remote IP. When zero, events are unlimited")
+	rateLimitBurst := flag.Int("rate-limit-burst", 20, "maximum number of events allowed at once by the rate limits")
+	rateLimitPolicy := flag.String("rate-limit-policy", "drop", "policy applied to the rate limited events i.e. drop, ack or disconnect")
+	maxFrameBytes := flag.Int("max-frame-bytes", socket.DefaultLimits().MaxFrameBytes, "maximum size of a received frame in bytes, where a larger frame disconnects the socket")
+	debugEvents := flag.Bool("debug-events", false, "whether to log all the events received and emitted by the sockets at the debug level, so requires -log-level debug. Toggle at runtime with POST /debug/events?enabled=true|false on the admin address")
+	batchFlushInterval := flag.Duration("batch-flush-interval", 0, "maximum duration to wait for more packets to coalesce into a batch. When zero, only the packets already queued are coalesced")
+	authToken := flag.String("auth-token", "", "token the clients must send to connect, in the \"Authorization\" header or the first packet. When empty, clients aren't authenticated")
+	tracing := flag.Bool("trace", false, "whether to log the trace spans of the events and room messages at the debug level")
+	flag.Parse()
+
+	var level slog.LevelVar
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		slog.Error("invalid log level", slog.String("level", *logLevel), slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	handlerOpts := &slog.HandlerOptions{
+		Level: &level,
+	}
+	var handler slog.Handler
+	switch *logFormat {
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, handlerOpts)
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, handlerOpts)
+	default:
+		slog.Error("invalid log format", slog.String("format", *logFormat))
+		os.Exit(1)
+	}
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+
+	if *brokerServeAddr != "" {
+		ln, err := net.Listen("tcp", *brokerServeAddr)
+		if err != nil {
+			logger.Error("unable to listen for the broker server", slog.Any("error", err))
+			os.Exit(1)
+		}
+
+		bs := broker.NewServer()
+		defer bs.Close()
+
+		go func() {
+			if err := bs.Serve(ln); err != nil {
+				logger.Info("broker server stopped", slog.Any("error", err))
+			}
+		}()
+	}
+
+	var tracer *trace.Tracer
+	if *tracing {
+		tracer = trace.NewTracer(trace.NewLogExporter(logger))
+	}
+
+	roomCfg := room.NewRoomConfig[socket.Args]()
+	roomCfg.Logger = logger
+	roomCfg.Tracer = tracer
+	roomCfg.HistorySize = 500
+	roomCfg.HistoryMaxAge = 24 * time.Hour
+	roomCfg.MaxClients = *roomMaxClients
+	if *storePath != "" {
+		storeCfg := store.NewFileConfig()
+		storeCfg.Retention = roomCfg.HistoryMaxAge
+
+		ms, err := store.OpenFile(*storePath, storeCfg)
+		if err != nil {
+			logger.Error("unable to open the message store", slog.Any("error", err))
+			os.Exit(1)
+		}
+		defer ms.Close()
+
+		roomCfg.Store = ms
+	}
+	if *brokerAddr != "" {
+		brokerCfg := broker.NewTCPConfig()
+		brokerCfg.Logger = logger
+
+		b, err := broker.Dial(*brokerAddr, brokerCfg)
+		if err != nil {
+			logger.Error("unable to connect to the broker", slog.Any("error", err))
+			os.Exit(1)
+		}
+		defer b.Close()
+
+		roomCfg.Broker = b
+	}
+
 	http.HandleFunc("/socket.js", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "./public/socket.js")
 	})
@@ -18,8 +133,86 @@ func main() {
 		http.ServeFile(w, r, "./public/index.html")
 	})
 
-	cs := NewChatServer()
+	socketCfg := socket.NewSocketConfig()
+	socketCfg.Logger = logger
+	socketCfg.Tracer = tracer
+	// Dispatch the handlers in order on a worker, so a slow "join" doesn't block receiving the acknowledgements
+	socketCfg.AsyncDispatch = true
+	socketCfg.DispatchOrdering = socket.OrderPerSocket
+	socketCfg.DebugEvents = new(atomic.Bool)
+	socketCfg.DebugEvents.Store(*debugEvents)
+	socketCfg.Limits.MaxFrameBytes = *maxFrameBytes
+	socketCfg.Capabilities = append(socketCfg.Capabilities, socket.CapabilityBatch, socket.CapabilityBinary, socket.CapabilityCompression)
+	socketCfg.BatchFlushInterval = *batchFlushInterval
+	if *authToken != "" {
+		socketCfg.AuthTimeout = 5 * time.Second
+		socketCfg.Middlewares = append(socketCfg.Middlewares, func(s *socket.Socket) error {
+			if subtle.ConstantTimeCompare([]byte(s.Handshake().Auth), []byte(*authToken)) != 1 {
+				return errInvalidAuthToken
+			}
+			return nil
+		})
+	}
+	if (*rateLimit > 0 || *ipRateLimit > 0) && *rateLimitBurst <= 0 {
+		logger.Error("invalid rate limit burst", slog.Int("burst", *rateLimitBurst))
+		os.Exit(1)
+	}
+	if *rateLimit > 0 {
+		socketCfg.RateLimit = &socket.RateLimit{
+			Rate:  *rateLimit,
+			Burst: *rateLimitBurst,
+		}
+	}
+	if *ipRateLimit > 0 {
+		socketCfg.IPRateLimiter = socket.NewIPRateLimiter(socket.RateLimit{
+			Rate:  *ipRateLimit,
+			Burst: *rateLimitBurst,
+		})
+	}
+	switch *rateLimitPolicy {
+	case "drop":
+		socketCfg.RateLimitPolicy = socket.RateLimitDrop
+	case "ack":
+		socketCfg.RateLimitPolicy = socket.RateLimitErrorAck
+	case "disconnect":
+		socketCfg.RateLimitPolicy = socket.RateLimitDisconnect
+	default:
+		logger.Error("invalid rate limit policy", slog.String("policy", *rateLimitPolicy))
+		os.Exit(1)
+	}
+
+	cs := NewChatServer(logger, roomCfg, socketCfg)
 	http.Handle("/chat", cs)
 
-	http.ListenAndServe(":10000", nil)
+	// The admin endpoints are served on a separate listener, so they're not exposed to the clients e.g. the stats
+	// include the names of the password protected rooms
+	if *adminAddr != "" {
+		adminMux := http.NewServeMux()
+		adminMux.HandleFunc("/stats", cs.ServeStats)
+		adminMux.Handle("/metrics", metrics.Default)
+		adminMux.HandleFunc("/debug/events", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+				if err != nil {
+					http.Error(w, "invalid enabled query parameter", http.StatusBadRequest)
+					return
+				}
+				socketCfg.DebugEvents.Store(enabled)
+				logger.Info("toggled the debug events", slog.Bool("enabled", enabled))
+			}
+			fmt.Fprintf(w, "%t\n", socketCfg.DebugEvents.Load())
+		})
+
+		go func() {
+			logger.Info("listening for the admin endpoints", slog.String("addr", *adminAddr))
+			if err := http.ListenAndServe(*adminAddr, adminMux); err != nil {
+				logger.Error("unable to listen for the admin endpoints", slog.Any("error", err))
+			}
+		}()
+	}
+
+	logger.Info("listening", slog.String("addr", *addr))
+	if err := http.ListenAndServe(*addr, nil); err != nil {
+		logger.Error("unable to listen", slog.Any("error", err))
+	}
 }
@@ -0,0 +1,25 @@
+package broker
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrBrokerClosed   = errors.New("broker: broker is closed")
+	ErrInvalidSubject = errors.New("broker: subject cannot be empty or contain whitespace")
+)
+
+// Broker defines a publish/subscribe transport, which allows messages to be fanned out across multiple nodes
+type Broker interface {
+	Publish(subject string, data []byte) error
+	Subscribe(subject string, fn func(data []byte)) (func(), error)
+	Close() error
+}
+
+func validateSubject(subject string) error {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return ErrInvalidSubject
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package broker
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_Memory(t *testing.T) {
+	b := NewMemory()
+
+	var got []string
+	unsubscribe, err := b.Subscribe("room.a", func(data []byte) {
+		got = append(got, string(data))
+	})
+	testhelpers.AssertNoError(t, err)
+
+	testhelpers.AssertNoError(t, b.Publish("room.a", []byte("first")))
+	testhelpers.AssertNoError(t, b.Publish("room.b", []byte("other")))
+	unsubscribe()
+	testhelpers.AssertNoError(t, b.Publish("room.a", []byte("second")))
+	testhelpers.AssertEqual(t, got, []string{"first"})
+
+	testhelpers.AssertError(t, b.Publish("room a", nil))
+	testhelpers.AssertNoError(t, b.Close())
+	testhelpers.AssertError(t, b.Publish("room.a", nil))
+}
+
+func Test_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	testhelpers.AssertNoError(t, err)
+
+	s := NewServer()
+	go s.Serve(ln)
+	defer s.Close()
+
+	b1, err := Dial(ln.Addr().String(), nil)
+	testhelpers.AssertNoError(t, err)
+	defer b1.Close()
+
+	b2, err := Dial(ln.Addr().String(), nil)
+	testhelpers.AssertNoError(t, err)
+	defer b2.Close()
+
+	msgCh := make(chan string, 1)
+	unsubscribe, err := b2.Subscribe("room.a", func(data []byte) {
+		select {
+		case msgCh <- string(data):
+		default:
+		}
+	})
+	testhelpers.AssertNoError(t, err)
+
+	// Wait for the subscription to be registered by the server, as the commands are sent by different connections
+	waitFor(t, func() bool {
+		testhelpers.AssertNoError(t, b1.Publish("room.a", []byte("hello\r\nworld")))
+		select {
+		case msg := <-msgCh:
+			testhelpers.AssertEqual(t, msg, "hello\r\nworld")
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	})
+
+	unsubscribe()
+	testhelpers.AssertNoError(t, b2.Close())
+	testhelpers.AssertError(t, b2.Publish("room.a", nil))
+}
+
+func Test_TCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	testhelpers.AssertNoError(t, err)
+	addr := ln.Addr().String()
+
+	s := NewServer()
+	go s.Serve(ln)
+
+	cfg := NewTCPConfig()
+	cfg.ReconnectMinBackoff = 10 * time.Millisecond
+	cfg.ReconnectMaxBackoff = 50 * time.Millisecond
+
+	b1, err := Dial(addr, cfg)
+	testhelpers.AssertNoError(t, err)
+	defer b1.Close()
+
+	b2, err := Dial(addr, cfg)
+	testhelpers.AssertNoError(t, err)
+	defer b2.Close()
+
+	msgCh := make(chan string, 1)
+	_, err = b2.Subscribe("room.a", func(data []byte) {
+		select {
+		case msgCh <- string(data):
+		default:
+		}
+	})
+	testhelpers.AssertNoError(t, err)
+
+	// Restart the server, where the clients reconnect and resubscribe
+	testhelpers.AssertNoError(t, s.Close())
+	ln, err = net.Listen("tcp", addr)
+	testhelpers.AssertNoError(t, err)
+
+	s = NewServer()
+	go s.Serve(ln)
+	defer s.Close()
+
+	waitFor(t, func() bool {
+		// Publishing fails until the client has reconnected
+		b1.Publish("room.a", []byte("hello"))
+		select {
+		case msg := <-msgCh:
+			testhelpers.AssertEqual(t, msg, "hello")
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	})
+}
+
+func waitFor(t testing.TB, fn func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if fn() {
+			return
+		}
+	}
+	t.Fatalf("timeout waiting for the condition")
+}
@@ -0,0 +1,93 @@
+package broker
+
+import (
+	"slices"
+	"sync"
+)
+
+type subscriber struct {
+	fn func(data []byte)
+}
+
+// Memory is an in-process broker, where subscribers are called synchronously when a message is published
+type Memory struct {
+	subscribers map[string][]*subscriber
+	closed      bool
+	mu          sync.RWMutex
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		subscribers: map[string][]*subscriber{},
+		closed:      false,
+	}
+}
+
+func (m *Memory) Publish(subject string, data []byte) error {
+	if err := validateSubject(subject); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrBrokerClosed
+	}
+	subs := slices.Clone(m.subscribers[subject])
+	m.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.fn(slices.Clone(data))
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(subject string, fn func(data []byte)) (func(), error) {
+	if err := validateSubject(subject); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrBrokerClosed
+	}
+
+	sub := &subscriber{
+		fn: fn,
+	}
+	m.subscribers[subject] = append(m.subscribers[subject], sub)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.unsubscribe(subject, sub)
+		})
+	}, nil
+}
+
+func (m *Memory) unsubscribe(subject string, sub *subscriber) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.subscribers[subject] = slices.DeleteFunc(m.subscribers[subject], func(s *subscriber) bool {
+		return s == sub
+	})
+	if len(m.subscribers[subject]) == 0 {
+		delete(m.subscribers, subject)
+	}
+}
+
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrBrokerClosed
+	}
+
+	m.closed = true
+	clear(m.subscribers)
+	return nil
+}
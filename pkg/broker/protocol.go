@@ -0,0 +1,83 @@
+package broker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The wire protocol is a minimal line based protocol similar to NATS, where each command is terminated by CRLF:
+//
+//	SUB <subject>
+//	UNSUB <subject>
+//	PUB <subject> <size>\r\n<payload>
+//	MSG <subject> <size>\r\n<payload>
+const (
+	opSub   = "SUB"
+	opUnsub = "UNSUB"
+	opPub   = "PUB"
+	opMsg   = "MSG"
+)
+
+// Maximum payload size allowed, which is 1MB
+const maxPayloadSize = 1 << 20
+
+type command struct {
+	op      string
+	subject string
+	payload []byte
+}
+
+func readCommand(r *bufio.Reader) (command, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return command{}, err
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return command{}, fmt.Errorf("broker: invalid command %q", strings.TrimSpace(line))
+	}
+
+	cmd := command{
+		op:      fields[0],
+		subject: fields[1],
+	}
+	switch cmd.op {
+	case opSub, opUnsub:
+		return cmd, nil
+	case opPub, opMsg:
+		if len(fields) != 3 {
+			return command{}, fmt.Errorf("broker: invalid %s command", cmd.op)
+		}
+
+		size, err := strconv.Atoi(fields[2])
+		if err != nil || size < 0 || size > maxPayloadSize {
+			return command{}, fmt.Errorf("broker: invalid %s payload size %q", cmd.op, fields[2])
+		}
+
+		// Read the payload and the trailing CRLF
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return command{}, err
+		}
+		cmd.payload = buf[:size]
+		return cmd, nil
+	default:
+		return command{}, fmt.Errorf("broker: unknown command %q", cmd.op)
+	}
+}
+
+func writeCommand(w *bufio.Writer, cmd command) error {
+	switch cmd.op {
+	case opPub, opMsg:
+		fmt.Fprintf(w, "%s %s %d\r\n", cmd.op, cmd.subject, len(cmd.payload))
+		w.Write(cmd.payload)
+		w.WriteString("\r\n")
+	default:
+		fmt.Fprintf(w, "%s %s\r\n", cmd.op, cmd.subject)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,174 @@
+package broker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// Server is a stand-in broker server, which routes published messages to all the connections subscribed to the subject
+type Server struct {
+	conns  map[*serverConn]empty
+	ln     net.Listener
+	closed bool
+	mu     sync.Mutex
+
+	wg sync.WaitGroup
+}
+
+type serverConn struct {
+	conn     net.Conn
+	w        *bufio.Writer
+	wmu      sync.Mutex
+	subjects map[string]empty
+}
+
+func NewServer() *Server {
+	return &Server{
+		conns: map[*serverConn]empty{},
+	}
+}
+
+// ListenAndServe listens on the TCP address and then calls Serve
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("broker: listening on %s: %w", addr, err)
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on the listener, until the server is closed
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrBrokerClosed
+	}
+	s.ln = ln
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrBrokerClosed
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			continue
+		}
+
+		sc := &serverConn{
+			conn:     conn,
+			w:        bufio.NewWriter(conn),
+			subjects: map[string]empty{},
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrBrokerClosed
+		}
+		s.conns[sc] = empty{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serveConn(sc)
+	}
+}
+
+func (s *Server) serveConn(sc *serverConn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, sc)
+		s.mu.Unlock()
+
+		sc.conn.Close()
+	}()
+
+	r := bufio.NewReader(sc.conn)
+	for {
+		cmd, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		switch cmd.op {
+		case opSub:
+			s.mu.Lock()
+			sc.subjects[cmd.subject] = empty{}
+			s.mu.Unlock()
+		case opUnsub:
+			s.mu.Lock()
+			delete(sc.subjects, cmd.subject)
+			s.mu.Unlock()
+		case opPub:
+			s.route(cmd.subject, cmd.payload)
+		}
+	}
+}
+
+func (s *Server) route(subject string, payload []byte) {
+	s.mu.Lock()
+	var dests []*serverConn
+	for sc := range s.conns {
+		if _, ok := sc.subjects[subject]; ok {
+			dests = append(dests, sc)
+		}
+	}
+	s.mu.Unlock()
+
+	msg := command{
+		op:      opMsg,
+		subject: subject,
+		payload: payload,
+	}
+	for _, sc := range dests {
+		sc.wmu.Lock()
+
+		// Ignore the error, as the connection will be cleaned up by its reader
+		writeCommand(sc.w, msg)
+		sc.wmu.Unlock()
+	}
+}
+
+// Addr returns the address the server is listening on
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrBrokerClosed
+	}
+	s.closed = true
+
+	var err error
+	if s.ln != nil {
+		err = s.ln.Close()
+	}
+	for sc := range s.conns {
+		sc.conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
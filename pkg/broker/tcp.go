@@ -0,0 +1,359 @@
+package broker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrBrokerDisconnected = errors.New("broker: not connected to the broker server")
+
+// TCPConfig defines the configuration settings for the TCP broker client
+type TCPConfig struct {
+	// Logger used to report the connection errors and the dropped messages. Default is slog.Default()
+	Logger *slog.Logger
+
+	// Delay before the first reconnection attempt, which is doubled after each failed attempt. Default is 100ms
+	ReconnectMinBackoff time.Duration
+
+	// Maximum delay between reconnection attempts. Default is 5s
+	ReconnectMaxBackoff time.Duration
+
+	// Maximum number of messages queued for a subscriber, where the messages are dropped when the queue is full,
+	// so a slow subscriber doesn't block the others. Default is 256
+	SubscriberQueueSize int
+}
+
+// NewTCPConfig initializes a TCP broker client configuration instance with reasonable defaults
+func NewTCPConfig() *TCPConfig {
+	return &TCPConfig{
+		Logger:              slog.Default(),
+		ReconnectMinBackoff: 100 * time.Millisecond,
+		ReconnectMaxBackoff: 5 * time.Second,
+		SubscriberQueueSize: 256,
+	}
+}
+
+// TCP is a broker client, which publishes and subscribes through a broker server over TCP.
+// When the connection is lost, then the client reconnects with an exponential backoff and resubscribes
+// to the subjects
+type TCP struct {
+	addr   string
+	cfg    *TCPConfig
+	logger *slog.Logger
+
+	// Conn is nil while reconnecting
+	conn net.Conn
+	w    *bufio.Writer
+	wmu  sync.Mutex
+
+	subscribers map[string][]*tcpSubscriber
+	closed      bool
+	mu          sync.RWMutex
+
+	closeCh chan empty
+	doneCh  chan empty
+}
+
+type empty struct{}
+
+// tcpSubscriber calls the function from its own goroutine, so the connection's reader is never blocked
+type tcpSubscriber struct {
+	fn     func(data []byte)
+	msgCh  chan []byte
+	stopCh chan empty
+}
+
+func newTCPSubscriber(fn func(data []byte), size int) *tcpSubscriber {
+	sub := &tcpSubscriber{
+		fn:     fn,
+		msgCh:  make(chan []byte, size),
+		stopCh: make(chan empty),
+	}
+	go sub.run()
+
+	return sub
+}
+
+func (s *tcpSubscriber) run() {
+	for {
+		select {
+		case data := <-s.msgCh:
+			s.fn(data)
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// Dial connects to the broker server at the address. When the configuration is nil, then the default
+// configuration is used
+func Dial(addr string, cfg *TCPConfig) (*TCP, error) {
+	if cfg == nil {
+		cfg = NewTCPConfig()
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("broker: dialing %s: %w", addr, err)
+	}
+
+	t := &TCP{
+		addr:   addr,
+		cfg:    cfg,
+		logger: cfg.Logger.With(slog.String("broker", addr)),
+
+		conn: conn,
+		w:    bufio.NewWriter(conn),
+
+		subscribers: map[string][]*tcpSubscriber{},
+		closed:      false,
+
+		closeCh: make(chan empty),
+		doneCh:  make(chan empty),
+	}
+	go t.run(conn)
+
+	return t, nil
+}
+
+// run reads from the connection, reconnecting until the client is closed
+func (t *TCP) run(conn net.Conn) {
+	defer close(t.doneCh)
+
+	for {
+		err := t.read(conn)
+		if t.isClosed() {
+			return
+		}
+		t.logger.Warn("lost the connection to the broker server", slog.Any("error", err))
+
+		t.wmu.Lock()
+		t.conn = nil
+		t.w = nil
+		t.wmu.Unlock()
+
+		if conn = t.reconnect(); conn == nil {
+			return
+		}
+		t.logger.Info("reconnected to the broker server")
+	}
+}
+
+func (t *TCP) read(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	for {
+		cmd, err := readCommand(r)
+		if err != nil {
+			return err
+		}
+		if cmd.op != opMsg {
+			continue
+		}
+
+		t.mu.RLock()
+		subs := slices.Clone(t.subscribers[cmd.subject])
+		t.mu.RUnlock()
+
+		for _, sub := range subs {
+			select {
+			case sub.msgCh <- slices.Clone(cmd.payload):
+			case <-sub.stopCh:
+			default:
+				t.logger.Warn("dropped the broker message, as the subscriber is not keeping up", slog.String("subject", cmd.subject))
+			}
+		}
+	}
+}
+
+// reconnect dials the broker server with an exponential backoff and resubscribes to the subjects.
+// When the client is closed, then nil is returned
+func (t *TCP) reconnect() net.Conn {
+	backoff := t.cfg.ReconnectMinBackoff
+	for {
+		select {
+		case <-time.After(backoff):
+		case <-t.closeCh:
+			return nil
+		}
+		backoff = min(backoff*2, t.cfg.ReconnectMaxBackoff)
+
+		conn, err := net.Dial("tcp", t.addr)
+		if err != nil {
+			t.logger.Warn("unable to reconnect to the broker server", slog.Any("error", err))
+			continue
+		}
+
+		t.mu.RLock()
+		subjects := make([]string, 0, len(t.subscribers))
+		for subject := range t.subscribers {
+			subjects = append(subjects, subject)
+		}
+		t.mu.RUnlock()
+
+		t.wmu.Lock()
+		w := bufio.NewWriter(conn)
+		for _, subject := range subjects {
+			err = writeCommand(w, command{
+				op:      opSub,
+				subject: subject,
+			})
+			if err != nil {
+				break
+			}
+		}
+		if err == nil {
+			t.conn = conn
+			t.w = w
+		}
+		t.wmu.Unlock()
+
+		if err != nil {
+			conn.Close()
+			t.logger.Warn("unable to resubscribe to the broker server", slog.Any("error", err))
+			continue
+		}
+
+		// The connection was established while closing, so it isn't closed by Close
+		if t.isClosed() {
+			conn.Close()
+			return nil
+		}
+		return conn
+	}
+}
+
+func (t *TCP) isClosed() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.closed
+}
+
+func (t *TCP) write(cmd command) error {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+
+	if t.w == nil {
+		return ErrBrokerDisconnected
+	}
+	if err := writeCommand(t.w, cmd); err != nil {
+		return fmt.Errorf("broker: writing %s command: %w", cmd.op, err)
+	}
+	return nil
+}
+
+func (t *TCP) Publish(subject string, data []byte) error {
+	if err := validateSubject(subject); err != nil {
+		return err
+	}
+	if t.isClosed() {
+		return ErrBrokerClosed
+	}
+
+	return t.write(command{
+		op:      opPub,
+		subject: subject,
+		payload: data,
+	})
+}
+
+// Subscribe subscribes to the subject. When the client is reconnecting, then the subscription is sent
+// to the broker server once reconnected
+func (t *TCP) Subscribe(subject string, fn func(data []byte)) (func(), error) {
+	if err := validateSubject(subject); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil, ErrBrokerClosed
+	}
+
+	sub := newTCPSubscriber(fn, t.cfg.SubscriberQueueSize)
+	isFirst := len(t.subscribers[subject]) == 0
+	t.subscribers[subject] = append(t.subscribers[subject], sub)
+	t.mu.Unlock()
+
+	if isFirst {
+		err := t.write(command{
+			op:      opSub,
+			subject: subject,
+		})
+		if err != nil && !errors.Is(err, ErrBrokerDisconnected) {
+			t.unsubscribe(subject, sub)
+			return nil, err
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.unsubscribe(subject, sub)
+		})
+	}, nil
+}
+
+func (t *TCP) unsubscribe(subject string, sub *tcpSubscriber) {
+	t.mu.Lock()
+	n := len(t.subscribers[subject])
+	t.subscribers[subject] = slices.DeleteFunc(t.subscribers[subject], func(s *tcpSubscriber) bool {
+		return s == sub
+	})
+	if len(t.subscribers[subject]) < n {
+		close(sub.stopCh)
+	}
+
+	isLast := len(t.subscribers[subject]) == 0
+	if isLast {
+		delete(t.subscribers, subject)
+	}
+	closed := t.closed
+	t.mu.Unlock()
+
+	if isLast && !closed {
+		// Ignore the error, as the connection is likely closed, where the subject isn't resubscribed to
+		// when reconnecting
+		t.write(command{
+			op:      opUnsub,
+			subject: subject,
+		})
+	}
+}
+
+func (t *TCP) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return ErrBrokerClosed
+	}
+	t.closed = true
+	for _, subs := range t.subscribers {
+		for _, sub := range subs {
+			close(sub.stopCh)
+		}
+	}
+	clear(t.subscribers)
+	t.mu.Unlock()
+
+	close(t.closeCh)
+
+	var err error
+	t.wmu.Lock()
+	if t.conn != nil {
+		err = t.conn.Close()
+	}
+	t.wmu.Unlock()
+	<-t.doneCh
+
+	if err != nil {
+		return fmt.Errorf("broker: closing connection: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metric defines a metric, which is written in the Prometheus text exposition format.
+// Idea based on URL: https://prometheus.io/docs/instrumenting/exposition_formats/#text-based-format
+type Metric interface {
+	Name() string
+	write(w io.Writer)
+}
+
+type desc struct {
+	name       string
+	help       string
+	typ        string
+	labelNames []string
+}
+
+func (d desc) Name() string {
+	return d.name
+}
+
+func (d desc) writeHeader(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.typ)
+}
+
+// value is a float64, which can be updated atomically
+type value struct {
+	bits atomic.Uint64
+}
+
+func (v *value) add(delta float64) {
+	for {
+		old := v.bits.Load()
+		next := math.Float64bits(math.Float64frombits(old) + delta)
+		if v.bits.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+func (v *value) set(f float64) {
+	v.bits.Store(math.Float64bits(f))
+}
+
+func (v *value) get() float64 {
+	return math.Float64frombits(v.bits.Load())
+}
+
+// Counter is a metric, which only increases
+type Counter struct {
+	desc
+	v value
+}
+
+func NewCounter(name, help string) *Counter {
+	return &Counter{
+		desc: desc{name: name, help: help, typ: "counter"},
+	}
+}
+
+func (c *Counter) Inc() {
+	c.v.add(1)
+}
+
+// Add increases the counter by the delta, where a negative delta is ignored
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	c.v.add(delta)
+}
+
+func (c *Counter) Value() float64 {
+	return c.v.get()
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.writeHeader(w)
+	writeSample(w, c.name, nil, nil, c.v.get())
+}
+
+// Gauge is a metric, which can increase and decrease
+type Gauge struct {
+	desc
+	v value
+}
+
+func NewGauge(name, help string) *Gauge {
+	return &Gauge{
+		desc: desc{name: name, help: help, typ: "gauge"},
+	}
+}
+
+func (g *Gauge) Set(f float64) {
+	g.v.set(f)
+}
+
+func (g *Gauge) Inc() {
+	g.v.add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.v.add(-1)
+}
+
+func (g *Gauge) Add(delta float64) {
+	g.v.add(delta)
+}
+
+func (g *Gauge) Value() float64 {
+	return g.v.get()
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.writeHeader(w)
+	writeSample(w, g.name, nil, nil, g.v.get())
+}
+
+// GaugeFunc is a gauge, where the value is returned by the function when written
+type GaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	return &GaugeFunc{
+		desc: desc{name: name, help: help, typ: "gauge"},
+		fn:   fn,
+	}
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	g.writeHeader(w)
+	writeSample(w, g.name, nil, nil, g.fn())
+}
+
+// DefaultBuckets are the default histogram buckets in seconds, which are suitable for latencies
+var DefaultBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Histogram is a metric, which counts the observations in cumulative buckets
+type Histogram struct {
+	desc
+	buckets []float64
+	counts  []atomic.Uint64
+	count   atomic.Uint64
+	sum     value
+}
+
+// NewHistogram initializes a histogram, where the buckets are the sorted upper bounds.
+// When the buckets are nil, then the default buckets are used
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &Histogram{
+		desc:    desc{name: name, help: help, typ: "histogram"},
+		buckets: buckets,
+		counts:  make([]atomic.Uint64, len(buckets)),
+	}
+}
+
+func (h *Histogram) Observe(f float64) {
+	idx := sort.SearchFloat64s(h.buckets, f)
+	if idx < len(h.counts) {
+		h.counts[idx].Add(1)
+	}
+	h.count.Add(1)
+	h.sum.add(f)
+}
+
+func (h *Histogram) Count() uint64 {
+	return h.count.Load()
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.writeHeader(w)
+	h.writeSamples(w, nil, nil)
+}
+
+func (h *Histogram) writeSamples(w io.Writer, labelNames, labelValues []string) {
+	bucketLabelNames := append(append([]string(nil), labelNames...), "le")
+
+	var cumulative uint64
+	for i, upper := range h.buckets {
+		cumulative += h.counts[i].Load()
+		bucketLabelValues := append(append([]string(nil), labelValues...), formatFloat(upper))
+		writeSample(w, h.name+"_bucket", bucketLabelNames, bucketLabelValues, float64(cumulative))
+	}
+
+	count := h.count.Load()
+	writeSample(w, h.name+"_bucket", bucketLabelNames, append(append([]string(nil), labelValues...), "+Inf"), float64(count))
+	writeSample(w, h.name+"_sum", labelNames, labelValues, h.sum.get())
+	writeSample(w, h.name+"_count", labelNames, labelValues, float64(count))
+}
+
+// CounterVec is a counter partitioned by the label values
+type CounterVec struct {
+	desc
+	counters map[string]*labeledCounter
+	mu       sync.RWMutex
+}
+
+type labeledCounter struct {
+	labelValues []string
+	c           *Counter
+}
+
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{
+		desc:     desc{name: name, help: help, typ: "counter", labelNames: labelNames},
+		counters: map[string]*labeledCounter{},
+	}
+}
+
+// WithLabelValues returns the counter for the label values, which must match the number of label names
+func (cv *CounterVec) WithLabelValues(labelValues ...string) *Counter {
+	if len(labelValues) != len(cv.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", cv.name, len(cv.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	cv.mu.RLock()
+	lc, ok := cv.counters[key]
+	cv.mu.RUnlock()
+	if ok {
+		return lc.c
+	}
+
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+
+	if lc, ok := cv.counters[key]; ok {
+		return lc.c
+	}
+	lc = &labeledCounter{
+		labelValues: append([]string(nil), labelValues...),
+		c:           NewCounter(cv.name, cv.help),
+	}
+	cv.counters[key] = lc
+	return lc.c
+}
+
+func (cv *CounterVec) write(w io.Writer) {
+	cv.writeHeader(w)
+
+	cv.mu.RLock()
+	keys := make([]string, 0, len(cv.counters))
+	for key := range cv.counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lc := cv.counters[key]
+		writeSample(w, cv.name, cv.labelNames, lc.labelValues, lc.c.Value())
+	}
+	cv.mu.RUnlock()
+}
+
+func writeSample(w io.Writer, name string, labelNames, labelValues []string, v float64) {
+	if len(labelNames) == 0 {
+		fmt.Fprintf(w, "%s %s\n", name, formatFloat(v))
+		return
+	}
+
+	labels := make([]string, 0, len(labelNames))
+	for i, labelName := range labelNames {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, labelName, escapeLabelValue(labelValues[i])))
+	}
+	fmt.Fprintf(w, "%s{%s} %s\n", name, strings.Join(labels, ","), formatFloat(v))
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(strings.ToValidUTF8(s, "\uFFFD"))
+}
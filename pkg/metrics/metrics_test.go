@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_Registry(t *testing.T) {
+	r := NewRegistry()
+
+	c := NewCounter("test_total", "Total number of tests.")
+	g := NewGauge("test_active", "Number of active tests.")
+	cv := NewCounterVec("test_events_total", "Total number of events\nby name.", "event")
+	h := NewHistogram("test_latency_seconds", "Latency of the tests.", []float64{1, 0.5})
+	gf := NewGaugeFunc("test_func", "Value of a function.", func() float64 {
+		return 42
+	})
+
+	r.MustRegister(c, g, cv, h, gf)
+	testhelpers.AssertError(t, r.Register(NewCounter("test_total", "")))
+
+	c.Inc()
+	c.Add(2)
+	c.Add(-1)
+	g.Inc()
+	g.Inc()
+	g.Dec()
+	cv.WithLabelValues("message").Inc()
+	cv.WithLabelValues(`say "hi"`).Add(2)
+	h.Observe(0.25)
+	h.Observe(0.75)
+	h.Observe(5)
+
+	var buf bytes.Buffer
+	_, err := r.WriteTo(&buf)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, buf.String(), `# HELP test_active Number of active tests.
+# TYPE test_active gauge
+test_active 1
+# HELP test_events_total Total number of events\nby name.
+# TYPE test_events_total counter
+test_events_total{event="message"} 1
+test_events_total{event="say \"hi\""} 2
+# HELP test_func Value of a function.
+# TYPE test_func gauge
+test_func 42
+# HELP test_latency_seconds Latency of the tests.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{le="0.5"} 1
+test_latency_seconds_bucket{le="1"} 2
+test_latency_seconds_bucket{le="+Inf"} 3
+test_latency_seconds_sum 6
+test_latency_seconds_count 3
+# HELP test_total Total number of tests.
+# TYPE test_total counter
+test_total 3
+`)
+}
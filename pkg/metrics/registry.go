@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Registry holds the metrics, which are written in the Prometheus text exposition format
+type Registry struct {
+	metrics map[string]Metric
+	mu      sync.RWMutex
+}
+
+// Default is the registry used by the packages of this module
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: map[string]Metric{},
+	}
+}
+
+// Register adds the metrics to the registry, returning an error when a metric with the same name is already registered
+func (r *Registry) Register(ms ...Metric) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range ms {
+		if _, ok := r.metrics[m.Name()]; ok {
+			return fmt.Errorf("metrics: metric %s is already registered", m.Name())
+		}
+	}
+	for _, m := range ms {
+		r.metrics[m.Name()] = m
+	}
+	return nil
+}
+
+// MustRegister adds the metrics to the registry, panicking when a metric with the same name is already registered
+func (r *Registry) MustRegister(ms ...Metric) {
+	if err := r.Register(ms...); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister removes the metric from the registry
+func (r *Registry) Unregister(m Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.metrics, m.Name())
+}
+
+// WriteTo writes all the metrics ordered by name
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		r.metrics[name].write(&buf)
+	}
+	r.mu.RUnlock()
+
+	return buf.WriteTo(w)
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	// Ignore the error, as the client is likely unreachable
+	r.WriteTo(w)
+}
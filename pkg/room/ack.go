@@ -1,16 +1,15 @@
 package room
 
-import "sync"
+import "context"
 
 type ack struct {
-	closeOnce sync.Once
-	closed    bool
-	ch        chan error
+	ch chan error
 }
 
 func newACK() *ack {
 	return &ack{
-		ch: make(chan error),
+		// Buffered, so the room's event loop never blocks when the caller has stopped waiting e.g. on cancellation
+		ch: make(chan error, 1),
 	}
 }
 
@@ -18,19 +17,11 @@ func (a *ack) done(err error) {
 	a.ch <- err
 }
 
-func (a *ack) waiter() <-chan error {
-	return a.ch
-}
-
-func (a *ack) wait() error {
-	err := <-a.waiter()
-	a.close()
-	return err
-}
-
-func (a *ack) close() {
-	a.closeOnce.Do(func() {
-		close(a.ch)
-		a.closed = true
-	})
+func (a *ack) wait(ctx context.Context) error {
+	select {
+	case err := <-a.ch:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
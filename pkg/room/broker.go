@@ -0,0 +1,107 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"slices"
+)
+
+type brokerMessage[T any] struct {
+	// Origin is the ID of the room instance which published the message
+	Origin string     `json:"origin"`
+	Msg    Message[T] `json:"msg"`
+
+	// Exclude are the IDs of the clients the message is not sent to
+	Exclude []string `json:"exclude,omitempty"`
+
+	// SentRooms are the names of the rooms the message was already sent through, where the clients of
+	// these rooms are skipped, so a client in multiple target rooms receives the message once
+	SentRooms []string `json:"sentRooms,omitempty"`
+}
+
+func (r *Room[T]) subject() string {
+	return "room." + url.PathEscape(r.name)
+}
+
+func (r *Room[T]) subscribe() error {
+	if r.cfg.Broker == nil {
+		return nil
+	}
+
+	unsubscribe, err := r.cfg.Broker.Subscribe(r.subject(), r.onBrokerMessage)
+	if err != nil {
+		return fmt.Errorf("room: subscribing to the broker: %w", err)
+	}
+	r.unsubscribe = unsubscribe
+	return nil
+}
+
+func (r *Room[T]) publish(msg Message[T], exclude map[string]empty, sentRooms []string) error {
+	if r.cfg.Broker == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(brokerMessage[T]{
+		Origin:    r.id,
+		Msg:       msg,
+		Exclude:   slices.Collect(maps.Keys(exclude)),
+		SentRooms: sentRooms,
+	})
+	if err != nil {
+		return fmt.Errorf("room: encoding the broker message: %w", err)
+	}
+	if err := r.cfg.Broker.Publish(r.subject(), data); err != nil {
+		return fmt.Errorf("room: publishing to the broker: %w", err)
+	}
+	return nil
+}
+
+func (r *Room[T]) onBrokerMessage(data []byte) {
+	var bm brokerMessage[T]
+	if err := json.Unmarshal(data, &bm); err != nil {
+		r.logger.Warn("invalid broker message", slog.Any("error", err))
+		return
+	}
+
+	// Ignore the messages published by this room, as they have already been sent to the clients
+	if bm.Origin == r.id {
+		return
+	}
+
+	var exclude map[string]empty
+	if len(bm.Exclude) > 0 || len(bm.SentRooms) > 0 {
+		exclude = make(map[string]empty, len(bm.Exclude))
+		for _, id := range bm.Exclude {
+			exclude[id] = empty{}
+		}
+		r.excludeSentRooms(exclude, bm.SentRooms)
+	}
+	if _, err := r.deliver(context.Background(), nil, bm.Msg, exclude, nil); err != nil {
+		r.logger.Debug("unable to deliver the broker message", slog.String("message_id", bm.Msg.ID), slog.Any("error", err))
+	}
+}
+
+// excludeSentRooms adds the clients of this node's rooms the message was already sent through
+func (r *Room[T]) excludeSentRooms(exclude map[string]empty, sentRooms []string) {
+	if r.findRoom == nil {
+		return
+	}
+	for _, name := range sentRooms {
+		room, ok := r.findRoom(name)
+		if !ok || room == r {
+			continue
+		}
+
+		clients, err := room.Clients()
+		if err != nil {
+			continue
+		}
+		for _, client := range clients {
+			exclude[client.ID()] = empty{}
+		}
+	}
+}
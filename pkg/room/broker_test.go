@@ -0,0 +1,84 @@
+package room
+
+import (
+	"testing"
+	"time"
+
+	"github.com/softwarespot/chatterbox/pkg/broker"
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_RoomBroker(t *testing.T) {
+	b := broker.NewMemory()
+	defer b.Close()
+
+	cfg := NewRoomConfig[string]()
+	cfg.Broker = b
+
+	// Simulate two nodes, each with their own manager
+	nodeA := NewManager(cfg)
+	defer nodeA.Close()
+	nodeB := NewManager(cfg)
+	defer nodeB.Close()
+
+	rA := nodeA.Load("root", nil)
+	rB := nodeB.Load("root", nil)
+
+	cA, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+	cB, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+
+	testhelpers.AssertNoError(t, rA.Register(cA))
+	testhelpers.AssertNoError(t, rB.Register(cB))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- rA.Send(cA, "from node A")
+	}()
+	testhelpers.AssertEqual(t, receive(t, cB), "from node A")
+	testhelpers.AssertNoError(t, <-errCh)
+
+	go func() {
+		errCh <- rB.Broadcast("from node B")
+	}()
+	testhelpers.AssertEqual(t, receive(t, cB), "from node B")
+	testhelpers.AssertEqual(t, receive(t, cA), "from node B")
+	testhelpers.AssertNoError(t, <-errCh)
+}
+
+func Test_BroadcastOperatorBroker(t *testing.T) {
+	b := broker.NewMemory()
+	defer b.Close()
+
+	cfg := NewRoomConfig[string]()
+	cfg.Broker = b
+
+	// Simulate two nodes, each with their own manager
+	nodeA := NewManager(cfg)
+	defer nodeA.Close()
+	nodeB := NewManager(cfg)
+	defer nodeB.Close()
+
+	nodeA.Load("room1", nil)
+	nodeA.Load("room2", nil)
+
+	// Client B is in both rooms of node B, so it should only receive the message once
+	cB, err := NewBufferedClient[string](2)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertNoError(t, nodeB.Load("room1", nil).Register(cB))
+	testhelpers.AssertNoError(t, nodeB.Load("room2", nil).Register(cB))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- nodeA.To("room1", "room2").Send("hello")
+	}()
+	testhelpers.AssertEqual(t, receive(t, cB), "hello")
+	testhelpers.AssertNoError(t, <-errCh)
+
+	select {
+	case msg := <-cB.Messages():
+		t.Fatalf("expected the message to be received once, got %q", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -1,11 +1,15 @@
 package room
 
 import (
+	"context"
 	"errors"
 	"sync"
 )
 
-var ErrClientClosed = errors.New("room: client is closed")
+var (
+	ErrClientClosed = errors.New("room: client is closed")
+	ErrClientBusy   = errors.New("room: client is not ready to receive the message")
+)
 
 type Client[T any] struct {
 	id     string
@@ -16,10 +20,16 @@ type Client[T any] struct {
 }
 
 func NewClient[T any]() (*Client[T], error) {
+	return NewBufferedClient[T](0)
+}
+
+// NewBufferedClient initializes a client, which buffers up to the size of messages not yet received, so
+// sending doesn't wait for the client to receive and a volatile message is only dropped when the buffer is full
+func NewBufferedClient[T any](size int) (*Client[T], error) {
 	c := &Client[T]{
 		id:     "",
 		closed: false,
-		msgCh:  make(chan T),
+		msgCh:  make(chan T, max(0, size)),
 	}
 
 	var err error
@@ -34,6 +44,11 @@ func (c *Client[T]) ID() string {
 }
 
 func (c *Client[T]) Send(msg T) error {
+	return c.SendContext(context.Background(), msg)
+}
+
+// SendContext sends the message, aborting when the context is cancelled
+func (c *Client[T]) SendContext(ctx context.Context, msg T) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -41,8 +56,30 @@ func (c *Client[T]) Send(msg T) error {
 		return ErrClientClosed
 	}
 
-	c.msgCh <- msg
-	return nil
+	select {
+	case c.msgCh <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TrySend sends the message only when the client is ready to receive it or has space in its buffer,
+// otherwise ErrClientBusy is returned instead of blocking
+func (c *Client[T]) TrySend(msg T) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrClientClosed
+	}
+
+	select {
+	case c.msgCh <- msg:
+		return nil
+	default:
+		return ErrClientBusy
+	}
 }
 
 func (c *Client[T]) Messages() <-chan T {
@@ -1,17 +1,105 @@
 package room
 
-import "time"
+import (
+	"log/slog"
+	"time"
+
+	"github.com/softwarespot/chatterbox/pkg/broker"
+	"github.com/softwarespot/chatterbox/pkg/store"
+	"github.com/softwarespot/chatterbox/pkg/trace"
+)
 
 // Config defines the configuration settings for the WebSocket handler.
 type Config[T any] struct {
+	// Logger used by the room, where the "room" attribute is added. Default is slog.Default()
+	Logger *slog.Logger
+
+	// Tracer used to create a span for each message sent through the room. Default is nil i.e. tracing is disabled
+	Tracer *trace.Tracer
+
 	// How long to wait for all connected clients to gracefully close. Default is 30s
 	CloseTimeout time.Duration
+
+	// Broker used to fan out messages to the room's clients connected to other nodes.
+	// Messages are encoded as JSON. Default is nil i.e. messages are only sent to the clients of this node
+	Broker broker.Broker
+
+	// Maximum number of messages kept in the room's history. Default is 0 i.e. the history is disabled
+	HistorySize int
+
+	// Maximum age of the messages kept in the room's history. Default is 0 i.e. messages don't expire
+	HistoryMaxAge time.Duration
+
+	// Returns whether a message is kept in the room's history and persisted to the store e.g. to exclude
+	// notices. Default is nil i.e. all messages are kept
+	HistoryFilter func(msg T) bool
+
+	// Store used to persist the messages sent through the room, which are restored into the room's history
+	// when the room is created. Default is nil i.e. messages are not persisted
+	Store store.MessageStore
+
+	// Maximum number of clients allowed to register in the room. Default is 0 i.e. unlimited
+	MaxClients int
+
+	// Password required to register in the room. Default is empty i.e. no password is required
+	Password string
+
+	// Whether only the client IDs in the allow list are allowed to register in the room. Default is false
+	InviteOnly bool
+
+	// Client IDs allowed to register in the room when invite-only
+	AllowList []string
+
+	// Client IDs forbidden from registering in the room
+	BanList []string
+
+	// Returns the topics of a message, which are used to only send the message to the clients subscribed to
+	// one of the topics. Default is nil i.e. messages don't have topics
+	Topics func(msg T) []string
+
+	// Hooks are optional and are called from the room's event loop, therefore they must not block or
+	// call the room's methods synchronously
+
+	// Called after a client has been registered in the room
+	OnRegister func(r *Room[T], client *Client[T])
+
+	// Called after a client has been unregistered from the room
+	OnUnregister func(r *Room[T], client *Client[T])
+
+	// Called after a message has been sent to the room's clients
+	OnMessage func(r *Room[T], msg Message[T])
+
+	// Called after the room has been closed
+	OnClose func(r *Room[T])
 }
 
 // NewRoomConfig initializes a room configuration instance with reasonable defaults.
 func NewRoomConfig[T any]() *Config[T] {
 	cfg := &Config[T]{
+		Logger: slog.Default(),
+		Tracer: nil,
+
 		CloseTimeout: 30 * time.Second,
+		Broker:       nil,
+
+		HistorySize:   0,
+		HistoryMaxAge: 0,
+		HistoryFilter: nil,
+
+		Store: nil,
+
+		MaxClients: 0,
+		Password:   "",
+		InviteOnly: false,
+		AllowList:  nil,
+		BanList:    nil,
+
+		Topics: nil,
+
+		OnRegister:   nil,
+		OnUnregister: nil,
+		OnMessage:    nil,
+		OnClose:      nil,
 	}
 	return cfg
 }
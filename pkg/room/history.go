@@ -0,0 +1,119 @@
+package room
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Message is a message sent through a room, which is recorded in the room's history
+type Message[T any] struct {
+	ID string `json:"id"`
+
+	// SenderID is empty when the message was broadcast without a sender
+	SenderID string    `json:"senderId"`
+	Data     T         `json:"data"`
+	Topics   []string  `json:"topics,omitempty"`
+	Time     time.Time `json:"time"`
+
+	// Volatile messages are dropped for the clients not ready to receive them and aren't kept in the history
+	Volatile bool `json:"volatile,omitempty"`
+}
+
+// HistoryQuery defines the page of messages to return from a room's history
+type HistoryQuery struct {
+	// BeforeID is optional.
+	// When defined, only the messages sent before the message with this ID are returned
+	BeforeID string
+
+	// Before is optional.
+	// When defined, only the messages sent before this time are returned
+	Before time.Time
+
+	// Maximum number of the most recent messages to return. When zero, all messages are returned
+	Limit int
+}
+
+type history[T any] struct {
+	size   int
+	maxAge time.Duration
+
+	msgs []Message[T]
+	mu   sync.Mutex
+}
+
+func newHistory[T any](size int, maxAge time.Duration) *history[T] {
+	return &history[T]{
+		size:   size,
+		maxAge: maxAge,
+		msgs:   nil,
+	}
+}
+
+func (h *history[T]) enabled() bool {
+	return h.size > 0
+}
+
+func (h *history[T]) add(msg Message[T]) {
+	if !h.enabled() {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.msgs = append(h.msgs, msg)
+	if over := len(h.msgs) - h.size; over > 0 {
+		h.msgs = slices.Delete(h.msgs, 0, over)
+	}
+	h.prune()
+}
+
+func (h *history[T]) query(q HistoryQuery) []Message[T] {
+	if !h.enabled() {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.prune()
+
+	end := len(h.msgs)
+	if q.BeforeID != "" {
+		idx := slices.IndexFunc(h.msgs, func(msg Message[T]) bool {
+			return msg.ID == q.BeforeID
+		})
+		if idx == -1 {
+			return []Message[T]{}
+		}
+		end = idx
+	}
+	if !q.Before.IsZero() {
+		for end > 0 && !h.msgs[end-1].Time.Before(q.Before) {
+			end--
+		}
+	}
+
+	start := 0
+	if q.Limit > 0 {
+		start = max(0, end-q.Limit)
+	}
+	return slices.Clone(h.msgs[start:end])
+}
+
+// prune removes the messages older than the maximum age. The lock must be held by the caller
+func (h *history[T]) prune() {
+	if h.maxAge <= 0 {
+		return
+	}
+
+	cutoff := time.Now().Add(-h.maxAge)
+	idx := slices.IndexFunc(h.msgs, func(msg Message[T]) bool {
+		return msg.Time.After(cutoff)
+	})
+	if idx == -1 {
+		idx = len(h.msgs)
+	}
+	h.msgs = slices.Delete(h.msgs, 0, idx)
+}
@@ -0,0 +1,107 @@
+package room
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/softwarespot/chatterbox/pkg/store"
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_History(t *testing.T) {
+	h := newHistory[string](3, 0)
+
+	now := time.Now()
+	for i := range 5 {
+		h.add(Message[string]{
+			ID:   fmt.Sprint(i),
+			Data: fmt.Sprintf("msg %d", i),
+			Time: now.Add(time.Duration(i) * time.Second),
+		})
+	}
+
+	ids := func(msgs []Message[string]) []string {
+		var res []string
+		for _, msg := range msgs {
+			res = append(res, msg.ID)
+		}
+		return res
+	}
+
+	// Only the last 3 messages are kept
+	testhelpers.AssertEqual(t, ids(h.query(HistoryQuery{})), []string{"2", "3", "4"})
+	testhelpers.AssertEqual(t, ids(h.query(HistoryQuery{Limit: 2})), []string{"3", "4"})
+	testhelpers.AssertEqual(t, ids(h.query(HistoryQuery{BeforeID: "4", Limit: 1})), []string{"3"})
+	testhelpers.AssertEqual(t, ids(h.query(HistoryQuery{BeforeID: "0"})), []string(nil))
+	testhelpers.AssertEqual(t, h.query(HistoryQuery{BeforeID: "0"}), []Message[string]{})
+	testhelpers.AssertEqual(t, ids(h.query(HistoryQuery{Before: now.Add(4 * time.Second)})), []string{"2", "3"})
+
+	// Messages older than the maximum age are removed
+	h = newHistory[string](10, time.Minute)
+	h.add(Message[string]{ID: "old", Time: now.Add(-2 * time.Minute)})
+	h.add(Message[string]{ID: "new", Time: now})
+	testhelpers.AssertEqual(t, ids(h.query(HistoryQuery{})), []string{"new"})
+
+	// Disabled history
+	h = newHistory[string](0, 0)
+	h.add(Message[string]{ID: "ignored", Time: now})
+	testhelpers.AssertEqual(t, ids(h.query(HistoryQuery{})), []string(nil))
+}
+
+func Test_RoomHistory(t *testing.T) {
+	cfg := NewRoomConfig[string]()
+	cfg.HistorySize = 10
+
+	r := New("root", cfg)
+	defer r.Close()
+
+	c, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+
+	testhelpers.AssertNoError(t, r.Send(c, "first"))
+	testhelpers.AssertNoError(t, r.Broadcast("second"))
+
+	msgs := r.History(HistoryQuery{})
+	testhelpers.AssertEqual(t, len(msgs), 2)
+	testhelpers.AssertEqual(t, msgs[0].Data, "first")
+	testhelpers.AssertEqual(t, msgs[0].SenderID, c.ID())
+	testhelpers.AssertEqual(t, msgs[1].Data, "second")
+	testhelpers.AssertEqual(t, msgs[1].SenderID, "")
+}
+
+func Test_RoomHistoryFilter(t *testing.T) {
+	cfg := NewRoomConfig[string]()
+	cfg.HistorySize = 10
+	cfg.HistoryFilter = func(msg string) bool {
+		return msg != "notice"
+	}
+
+	r := New("root", cfg)
+	defer r.Close()
+
+	testhelpers.AssertNoError(t, r.Broadcast("notice"))
+	testhelpers.AssertNoError(t, r.Broadcast("kept"))
+
+	msgs := r.History(HistoryQuery{})
+	testhelpers.AssertEqual(t, len(msgs), 1)
+	testhelpers.AssertEqual(t, msgs[0].Data, "kept")
+}
+
+func Test_RoomHistoryStore(t *testing.T) {
+	cfg := NewRoomConfig[string]()
+	cfg.HistorySize = 10
+	cfg.Store = store.NewMemory()
+
+	r := New("root", cfg)
+	testhelpers.AssertNoError(t, r.Broadcast("persisted"))
+	testhelpers.AssertNoError(t, r.Close())
+
+	// Simulate a restart, where the history is restored from the store
+	r = New("root", cfg)
+	defer r.Close()
+
+	msgs := r.History(HistoryQuery{})
+	testhelpers.AssertEqual(t, len(msgs), 1)
+	testhelpers.AssertEqual(t, msgs[0].Data, "persisted")
+}
@@ -0,0 +1,44 @@
+package room
+
+import (
+	"testing"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_RoomHooks(t *testing.T) {
+	var events []string
+
+	cfg := NewRoomConfig[string]()
+	cfg.OnRegister = func(r *Room[string], client *Client[string]) {
+		events = append(events, "register")
+	}
+	cfg.OnUnregister = func(r *Room[string], client *Client[string]) {
+		events = append(events, "unregister")
+	}
+	cfg.OnMessage = func(r *Room[string], msg Message[string]) {
+		events = append(events, "message "+msg.Data)
+	}
+	cfg.OnClose = func(r *Room[string]) {
+		events = append(events, "close "+r.Name())
+	}
+
+	r := New("root", cfg)
+
+	c, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+
+	testhelpers.AssertNoError(t, r.Register(c))
+	testhelpers.AssertNoError(t, r.Register(c))
+	testhelpers.AssertNoError(t, r.Send(c, "hello"))
+	testhelpers.AssertNoError(t, r.Unregister(c))
+	testhelpers.AssertNoError(t, r.Unregister(c))
+	testhelpers.AssertNoError(t, r.Close())
+
+	testhelpers.AssertEqual(t, events, []string{
+		"register",
+		"message hello",
+		"unregister",
+		"close root",
+	})
+}
@@ -1,18 +1,67 @@
 package room
 
-import "sync"
+import (
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+var ErrManagerClientNotFound = errors.New("room: client not found")
 
 type Manager[T any] struct {
+	id     string
+	cfg    *Config[T]
+	logger *slog.Logger
+
 	rooms map[string]*Room[T]
-	mu    sync.Mutex
+
+	// Rooms of the topic patterns, which are kept apart from the rooms, so a room is never a topic subscription
+	topics   map[string]*Room[T]
+	topicCfg *Config[T]
+
+	clients     map[string]*Client[T]
+	unsubscribe func()
+	mu          sync.Mutex
 }
 
-func NewManager[T any]() *Manager[T] {
-	return &Manager[T]{
+// NewManager initializes a manager, where the configuration is used for the rooms loaded without a configuration
+func NewManager[T any](cfg *Config[T]) *Manager[T] {
+	if cfg == nil {
+		cfg = NewRoomConfig[T]()
+	}
+	// Ignore the error, as the ID is only used to identify the topic messages published to the broker by this manager
+	id, _ := createID()
+
+	// Topic messages are only fanned out to the subscribers, therefore they're not kept or published by the rooms
+	topicCfg := NewRoomConfig[T]()
+	topicCfg.Logger = cfg.Logger
+	topicCfg.Tracer = cfg.Tracer
+	topicCfg.CloseTimeout = cfg.CloseTimeout
+
+	m := &Manager[T]{
+		id:     id,
+		cfg:    cfg,
+		logger: cfg.Logger,
+
 		rooms: map[string]*Room[T]{},
+
+		topics:   map[string]*Room[T]{},
+		topicCfg: topicCfg,
+
+		clients:     map[string]*Client[T]{},
+		unsubscribe: nil,
 	}
+
+	// The topics still work for the clients of this node
+	if err := m.subscribeTopics(); err != nil {
+		m.logger.Warn("unable to subscribe to the broker for the topics", slog.Any("error", err))
+	}
+	return m
 }
 
+// Load returns the room with the name, which is created using the configuration when it doesn't exist,
+// so each room can have its own policy e.g. password. When the configuration is nil, then the manager's
+// configuration is used. The configuration is ignored when the room already exists
 func (m *Manager[T]) Load(name string, cfg *Config[T]) *Room[T] {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -22,28 +71,107 @@ func (m *Manager[T]) Load(name string, cfg *Config[T]) *Room[T] {
 		return room
 	}
 
-	room = New(name, cfg)
+	if cfg == nil {
+		cfg = m.cfg
+	}
+	room = newRoom(name, cfg, m.room)
 	m.rooms[name] = room
 	return room
 }
 
+// AddClient adds the client to the manager, so it can be addressed directly by its ID
+func (m *Manager[T]) AddClient(client *Client[T]) error {
+	if client == nil {
+		return ErrRoomClientNil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.clients[client.ID()] = client
+	return nil
+}
+
+// RemoveClient removes the client from the manager
+func (m *Manager[T]) RemoveClient(client *Client[T]) error {
+	if client == nil {
+		return ErrRoomClientNil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.clients, client.ID())
+	return nil
+}
+
+// Client returns the client added to the manager with the ID
+func (m *Manager[T]) Client(id string) (*Client[T], bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client, ok := m.clients[id]
+	return client, ok
+}
+
+// SendTo sends the message directly to the client with the ID
+func (m *Manager[T]) SendTo(id string, msg T) error {
+	client, ok := m.Client(id)
+	if !ok {
+		return ErrManagerClientNotFound
+	}
+	return client.Send(msg)
+}
+
+// To returns a broadcast operator targeting the rooms
+func (m *Manager[T]) To(rooms ...string) *BroadcastOperator[T] {
+	return newBroadcastOperator(m).To(rooms...)
+}
+
+// ToClient returns a broadcast operator targeting the clients added to the manager with the IDs
+func (m *Manager[T]) ToClient(ids ...string) *BroadcastOperator[T] {
+	return newBroadcastOperator(m).ToClient(ids...)
+}
+
+// Except returns a broadcast operator excluding the client IDs
+func (m *Manager[T]) Except(ids ...string) *BroadcastOperator[T] {
+	return newBroadcastOperator(m).Except(ids...)
+}
+
+func (m *Manager[T]) room(name string) (*Room[T], bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	room, ok := m.rooms[name]
+	return room, ok
+}
+
 func (m *Manager[T]) Close() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.unsubscribe != nil {
+		m.unsubscribe()
+		m.unsubscribe = nil
+	}
+
 	var wg sync.WaitGroup
-	for _, room := range m.rooms {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
+	for _, rooms := range []map[string]*Room[T]{m.rooms, m.topics} {
+		for _, room := range rooms {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
 
-			// Ignore the error
-			room.Close()
-		}()
+				// Ignore the error
+				room.Close()
+			}()
+		}
 	}
 
 	wg.Wait()
 	clear(m.rooms)
+	clear(m.topics)
+	clear(m.clients)
 
 	return nil
 }
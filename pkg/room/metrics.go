@@ -0,0 +1,17 @@
+package room
+
+import "github.com/softwarespot/chatterbox/pkg/metrics"
+
+var (
+	activeRoomsGauge  = metrics.NewGauge("room_active", "Number of rooms which haven't been closed.")
+	roomMessagesTotal = metrics.NewCounter("room_messages_total", "Total number of messages sent through the rooms.")
+	roomFanoutHist    = metrics.NewHistogram("room_fanout_duration_seconds", "Duration of sending a message to all the clients of a room.", nil)
+)
+
+func init() {
+	metrics.Default.MustRegister(
+		activeRoomsGauge,
+		roomMessagesTotal,
+		roomFanoutHist,
+	)
+}
@@ -0,0 +1,38 @@
+package room
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/softwarespot/chatterbox/pkg/metrics"
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_RoomMetrics(t *testing.T) {
+	activeRooms := activeRoomsGauge.Value()
+	messages := roomMessagesTotal.Value()
+	fanouts := roomFanoutHist.Count()
+
+	r := New[string]("root", nil)
+	testhelpers.AssertEqual(t, activeRoomsGauge.Value(), activeRooms+1)
+
+	c1, err := NewBufferedClient[string](1)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertNoError(t, r.Register(c1))
+
+	testhelpers.AssertNoError(t, r.Broadcast("hello"))
+	testhelpers.AssertEqual(t, receive(t, c1), "hello")
+	testhelpers.AssertEqual(t, roomMessagesTotal.Value(), messages+1)
+	testhelpers.AssertEqual(t, roomFanoutHist.Count(), fanouts+1)
+
+	testhelpers.AssertNoError(t, r.Close())
+	testhelpers.AssertEqual(t, activeRoomsGauge.Value(), activeRooms)
+
+	var buf bytes.Buffer
+	_, err = metrics.Default.WriteTo(&buf)
+	testhelpers.AssertNoError(t, err)
+	for _, series := range []string{"room_active ", "room_messages_total ", "room_fanout_duration_seconds_count "} {
+		testhelpers.AssertEqual(t, strings.Contains(buf.String(), "\n"+series), true)
+	}
+}
@@ -0,0 +1,182 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// BroadcastOperator targets a set of rooms and/or clients, excluding a set of client IDs.
+// A client registered in multiple target rooms receives the message once. The rooms and client IDs are
+// targeted separately, so a room named after a client ID doesn't receive the client's messages.
+// Idea based on URL: https://socket.io/docs/v4/server-api/#broadcastoperator
+type BroadcastOperator[T any] struct {
+	m         *Manager[T]
+	rooms     []string
+	clientIDs []string
+	exclude   map[string]empty
+	volatile  bool
+}
+
+func newBroadcastOperator[T any](m *Manager[T]) *BroadcastOperator[T] {
+	return &BroadcastOperator[T]{
+		m:         m,
+		rooms:     nil,
+		clientIDs: nil,
+		exclude:   map[string]empty{},
+		volatile:  false,
+	}
+}
+
+// To returns a new operator, which additionally targets the rooms
+func (b *BroadcastOperator[T]) To(rooms ...string) *BroadcastOperator[T] {
+	op := b.clone()
+	op.rooms = append(op.rooms, rooms...)
+	return op
+}
+
+// ToClient returns a new operator, which additionally targets the clients added to the manager with the IDs
+func (b *BroadcastOperator[T]) ToClient(ids ...string) *BroadcastOperator[T] {
+	op := b.clone()
+	op.clientIDs = append(op.clientIDs, ids...)
+	return op
+}
+
+// Except returns a new operator, which additionally excludes the client IDs
+func (b *BroadcastOperator[T]) Except(ids ...string) *BroadcastOperator[T] {
+	op := b.clone()
+	for _, id := range ids {
+		op.exclude[id] = empty{}
+	}
+	return op
+}
+
+// Volatile returns a new operator, where the message is dropped for the clients not ready to receive it
+// instead of blocking
+func (b *BroadcastOperator[T]) Volatile() *BroadcastOperator[T] {
+	op := b.clone()
+	op.volatile = true
+	return op
+}
+
+// Clients returns the de-duplicated clients targeted by the operator
+func (b *BroadcastOperator[T]) Clients() ([]*Client[T], error) {
+	seen := map[*Client[T]]empty{}
+	var clients []*Client[T]
+	add := func(client *Client[T]) {
+		if _, ok := seen[client]; ok {
+			return
+		}
+		seen[client] = empty{}
+
+		if _, ok := b.exclude[client.ID()]; ok {
+			return
+		}
+		clients = append(clients, client)
+	}
+
+	for _, name := range b.rooms {
+		room, ok := b.m.room(name)
+		if !ok {
+			continue
+		}
+
+		roomClients, err := room.Clients()
+		if err != nil {
+			if errors.Is(err, ErrRoomClosed) {
+				continue
+			}
+			return nil, err
+		}
+		for _, client := range roomClients {
+			add(client)
+		}
+	}
+	for _, id := range b.clientIDs {
+		if client, ok := b.m.Client(id); ok {
+			add(client)
+		}
+	}
+	return clients, nil
+}
+
+// Send sends the message to all the targeted clients
+func (b *BroadcastOperator[T]) Send(msg T) error {
+	return b.SendContext(context.Background(), msg)
+}
+
+// SendContext sends the message to all the targeted clients, aborting when the context is cancelled.
+// The message is sent through each target room, so it's subject to the room's filters, history and broker,
+// where the errors of the target rooms and clients are joined
+func (b *BroadcastOperator[T]) SendContext(ctx context.Context, msg T) error {
+	seen := map[string]empty{}
+
+	var sentRooms []string
+	var errs []error
+	for _, name := range b.rooms {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+
+		room, ok := b.m.room(name)
+		if !ok {
+			continue
+		}
+
+		_, err := room.send(ctx, nil, msg, sendOptions{
+			volatile:  b.volatile,
+			exclude:   b.exclude,
+			seen:      seen,
+			sentRooms: slices.Clone(sentRooms),
+		})
+		sentRooms = append(sentRooms, name)
+		if err != nil && !errors.Is(err, ErrRoomClosed) {
+			errs = append(errs, fmt.Errorf("room: sending to room %q: %w", room.Name(), err))
+		}
+	}
+	for _, id := range b.clientIDs {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+
+		client, ok := b.m.Client(id)
+		if !ok {
+			continue
+		}
+		if err := b.sendClient(ctx, client, msg, seen); err != nil {
+			errs = append(errs, fmt.Errorf("room: sending to client %q: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *BroadcastOperator[T]) sendClient(ctx context.Context, client *Client[T], msg T, seen map[string]empty) error {
+	if _, ok := b.exclude[client.ID()]; ok {
+		return nil
+	}
+	if _, ok := seen[client.ID()]; ok {
+		return nil
+	}
+	seen[client.ID()] = empty{}
+
+	if b.volatile {
+		err := client.TrySend(msg)
+		if errors.Is(err, ErrClientBusy) {
+			return nil
+		}
+		return err
+	}
+	return client.SendContext(ctx, msg)
+}
+
+func (b *BroadcastOperator[T]) clone() *BroadcastOperator[T] {
+	return &BroadcastOperator[T]{
+		m:         b.m,
+		rooms:     slices.Clone(b.rooms),
+		clientIDs: slices.Clone(b.clientIDs),
+		exclude:   maps.Clone(b.exclude),
+		volatile:  b.volatile,
+	}
+}
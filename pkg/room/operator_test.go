@@ -0,0 +1,114 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_BroadcastOperator(t *testing.T) {
+	m := NewManager[string](nil)
+	defer m.Close()
+
+	r1 := m.Load("room1", nil)
+	r2 := m.Load("room2", nil)
+
+	c1, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+	c2, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+	c3, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+
+	// Client 1 is in both rooms, so it should only receive the message once
+	testhelpers.AssertNoError(t, r1.Register(c1))
+	testhelpers.AssertNoError(t, r2.Register(c1))
+	testhelpers.AssertNoError(t, r2.Register(c2))
+	testhelpers.AssertNoError(t, r2.Register(c3))
+
+	clients, err := m.To("room1", "room2").Except(c3.ID()).Clients()
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, len(clients), 2)
+	testhelpers.AssertEqual(t, slices.Contains(clients, c1), true)
+	testhelpers.AssertEqual(t, slices.Contains(clients, c2), true)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- m.To("room1", "room2").Except(c3.ID()).Send("hello")
+	}()
+	testhelpers.AssertEqual(t, receive(t, c1), "hello")
+	testhelpers.AssertEqual(t, receive(t, c2), "hello")
+	testhelpers.AssertNoError(t, <-errCh)
+
+	// Direct messaging by client ID
+	testhelpers.AssertError(t, m.SendTo(c3.ID(), "private"))
+	testhelpers.AssertNoError(t, m.AddClient(c3))
+	go func() {
+		errCh <- m.SendTo(c3.ID(), "private")
+	}()
+	testhelpers.AssertEqual(t, receive(t, c3), "private")
+	testhelpers.AssertNoError(t, <-errCh)
+
+	clients, err = m.ToClient(c3.ID()).Clients()
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, clients, []*Client[string]{c3})
+
+	// A room named after a client ID doesn't take over the client's messages
+	m.Load(c3.ID(), nil)
+	clients, err = m.ToClient(c3.ID()).Clients()
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, clients, []*Client[string]{c3})
+
+	clients, err = m.To(c3.ID()).Clients()
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, len(clients), 0)
+}
+
+func Test_BroadcastOperatorRoomLoop(t *testing.T) {
+	cfg := NewRoomConfig[string]()
+	cfg.HistorySize = 10
+
+	m := NewManager[string](cfg)
+	defer m.Close()
+
+	r1 := m.Load("room1", nil)
+
+	c1, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertNoError(t, r1.Register(c1))
+
+	// The message is sent through the room, so it's kept in the history and counted in the statistics
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- m.To("room1").Send("hello")
+	}()
+	testhelpers.AssertEqual(t, receive(t, c1), "hello")
+	testhelpers.AssertNoError(t, <-errCh)
+	testhelpers.AssertEqual(t, len(r1.History(HistoryQuery{})), 1)
+	testhelpers.AssertEqual(t, r1.Stats().MessagesIn, uint64(1))
+
+	// The client isn't receiving, so the error of the room is returned once the context is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	testhelpers.AssertEqual(t, errors.Is(m.To("room1").SendContext(ctx, "blocked"), context.DeadlineExceeded), true)
+	testhelpers.AssertEqual(t, receive(t, c1), "blocked")
+
+	// The volatile message is dropped for the client not ready to receive it
+	testhelpers.AssertNoError(t, m.To("room1").Volatile().Send("typing"))
+}
+
+func receive[T any](t testing.TB, c *Client[T]) T {
+	t.Helper()
+	select {
+	case msg := <-c.Messages():
+		return msg
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for a message")
+	}
+	var v T
+	return v
+}
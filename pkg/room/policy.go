@@ -0,0 +1,114 @@
+package room
+
+import (
+	"crypto/subtle"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrRoomFull      = errors.New("room: room is full")
+	ErrRoomForbidden = errors.New("room: client is forbidden from joining the room")
+	ErrRoomPassword  = errors.New("room: invalid room password")
+)
+
+type policy struct {
+	maxClients int
+	password   string
+	inviteOnly bool
+	allowed    map[string]empty
+	banned     map[string]empty
+	mu         sync.RWMutex
+}
+
+func newPolicy[T any](cfg *Config[T]) *policy {
+	p := &policy{
+		maxClients: cfg.MaxClients,
+		password:   cfg.Password,
+		inviteOnly: cfg.InviteOnly,
+		allowed:    map[string]empty{},
+		banned:     map[string]empty{},
+	}
+	for _, id := range cfg.AllowList {
+		p.allowed[id] = empty{}
+	}
+	for _, id := range cfg.BanList {
+		p.banned[id] = empty{}
+	}
+	return p
+}
+
+// check returns an error when the client isn't allowed to join the room with the current number of clients
+func (p *policy) check(id, password string, size int) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if _, ok := p.banned[id]; ok {
+		return ErrRoomForbidden
+	}
+	if p.inviteOnly {
+		if _, ok := p.allowed[id]; !ok {
+			return ErrRoomForbidden
+		}
+	}
+	if p.password != "" && subtle.ConstantTimeCompare([]byte(p.password), []byte(password)) != 1 {
+		return ErrRoomPassword
+	}
+	if p.maxClients > 0 && size >= p.maxClients {
+		return ErrRoomFull
+	}
+	return nil
+}
+
+func (p *policy) allow(id string, allowed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if allowed {
+		p.allowed[id] = empty{}
+	} else {
+		delete(p.allowed, id)
+	}
+}
+
+func (p *policy) ban(id string, banned bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if banned {
+		p.banned[id] = empty{}
+	} else {
+		delete(p.banned, id)
+	}
+}
+
+// Allow adds the client ID to the room's allow list, which is used when the room is invite-only
+func (r *Room[T]) Allow(id string) {
+	r.policy.allow(id, true)
+}
+
+// Disallow removes the client ID from the room's allow list
+func (r *Room[T]) Disallow(id string) {
+	r.policy.allow(id, false)
+}
+
+// Ban adds the client ID to the room's ban list, unregistering the client when registered
+func (r *Room[T]) Ban(id string) error {
+	r.policy.ban(id, true)
+
+	clients, err := r.Clients()
+	if err != nil {
+		return err
+	}
+	for _, client := range clients {
+		if client.ID() == id {
+			return r.Unregister(client)
+		}
+	}
+	return nil
+}
+
+// Unban removes the client ID from the room's ban list
+func (r *Room[T]) Unban(id string) {
+	r.policy.ban(id, false)
+}
@@ -0,0 +1,72 @@
+package room
+
+import (
+	"errors"
+	"testing"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_RoomPolicy(t *testing.T) {
+	newClient := func() *Client[string] {
+		c, err := NewClient[string]()
+		testhelpers.AssertNoError(t, err)
+		return c
+	}
+	c1 := newClient()
+	c2 := newClient()
+	c3 := newClient()
+
+	cfg := NewRoomConfig[string]()
+	cfg.MaxClients = 1
+	cfg.Password = "secret"
+	cfg.BanList = []string{c3.ID()}
+
+	r := New("root", cfg)
+	defer r.Close()
+
+	testhelpers.AssertEqual(t, errors.Is(r.Register(c1), ErrRoomPassword), true)
+	testhelpers.AssertNoError(t, r.RegisterWithPassword(c1, "secret"))
+
+	// Registering again doesn't count towards the maximum number of clients
+	testhelpers.AssertNoError(t, r.RegisterWithPassword(c1, "secret"))
+	testhelpers.AssertEqual(t, errors.Is(r.RegisterWithPassword(c2, "secret"), ErrRoomFull), true)
+	testhelpers.AssertEqual(t, errors.Is(r.RegisterWithPassword(c3, "secret"), ErrRoomForbidden), true)
+
+	testhelpers.AssertNoError(t, r.Ban(c1.ID()))
+	testhelpers.AssertEqual(t, r.Size(), 0)
+	testhelpers.AssertEqual(t, errors.Is(r.RegisterWithPassword(c1, "secret"), ErrRoomForbidden), true)
+
+	r.Unban(c3.ID())
+	testhelpers.AssertNoError(t, r.RegisterWithPassword(c3, "secret"))
+
+	cfg = NewRoomConfig[string]()
+	cfg.InviteOnly = true
+	cfg.AllowList = []string{c1.ID()}
+
+	r = New("invite-only", cfg)
+	defer r.Close()
+
+	testhelpers.AssertNoError(t, r.Register(c1))
+	testhelpers.AssertEqual(t, errors.Is(r.Register(c2), ErrRoomForbidden), true)
+
+	r.Allow(c2.ID())
+	testhelpers.AssertNoError(t, r.Register(c2))
+}
+
+func Test_ManagerRoomPolicy(t *testing.T) {
+	m := NewManager[string](nil)
+	defer m.Close()
+
+	c, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+
+	cfg := NewRoomConfig[string]()
+	cfg.Password = "secret"
+
+	// Each room has its own policy, where the configuration is ignored when the room already exists
+	protected := m.Load("protected", cfg)
+	testhelpers.AssertEqual(t, m.Load("protected", nil), protected)
+	testhelpers.AssertEqual(t, errors.Is(protected.Register(c), ErrRoomPassword), true)
+	testhelpers.AssertNoError(t, m.Load("public", nil).Register(c))
+}
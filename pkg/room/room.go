@@ -1,7 +1,9 @@
 package room
 
 import (
+	"context"
 	"errors"
+	"log/slog"
 	"sync/atomic"
 	"time"
 )
@@ -16,18 +18,53 @@ type empty struct{}
 
 type roomRegistration[T any] struct {
 	client *Client[T]
-	ack    *ack
+
+	// Password is only used when registering
+	password string
+	ack      *ack
 }
 
 type roomMessage[T any] struct {
-	msg T
+	msg Message[T]
 
 	// Sender is optional.
 	// When not defined with a client, the message is sent to ALL clients
 	sender *Client[T]
 
+	// Exclude is optional.
+	// Clients with an ID in the set are skipped
+	exclude map[string]empty
+
+	// Seen is optional.
+	// Clients with an ID in the set are skipped, where the IDs of the clients the message was sent to are added
+	// by the room's event loop, so a message sent through multiple rooms is received once
+	seen map[string]empty
+
 	// Acknowledgement is optional
 	ack *ack
+
+	// Report is filled by the room's event loop before acknowledging
+	report *DeliveryReport
+}
+
+// DeliveryReport is the result of sending a message to the room's clients connected to this node
+type DeliveryReport struct {
+	// Number of clients the message was delivered to
+	Delivered int
+
+	// Number of clients the message was not sent to, as they're not subscribed to the message's topics
+	Filtered int
+
+	// Number of clients a volatile message was dropped for, as they were not ready to receive it
+	Dropped int
+
+	// Errors of the clients the message failed to be delivered to, keyed by the client ID.
+	// Clients which have been closed are automatically unregistered from the room
+	Failed map[string]error
+}
+
+type roomClients[T any] struct {
+	clientsCh chan []*Client[T]
 }
 
 type roomClose struct {
@@ -35,78 +72,234 @@ type roomClose struct {
 }
 
 type Room[T any] struct {
-	name string
-	cfg  *Config[T]
+	id     string
+	name   string
+	cfg    *Config[T]
+	logger *slog.Logger
+
+	unsubscribe func()
+
+	// Optional and returns the room of the manager with the name, so the clients of the rooms a broker message
+	// was already sent through are skipped
+	findRoom func(name string) (*Room[T], bool)
 
+	// Closing is set when closing has been requested, and closed when the room's event loop has been stopped
+	closing      atomic.Bool
 	closed       atomic.Bool
+	doneCh       chan empty
 	closeCh      chan roomClose
 	registerCh   chan roomRegistration[T]
 	unregisterCh chan roomRegistration[T]
+	clientsCh    chan roomClients[T]
 
-	clients map[*Client[T]]empty
+	subscriptionCh chan roomSubscription[T]
+
+	clients map[*Client[T]]*subscription[T]
 	size    atomic.Int64
 
-	msgCh chan roomMessage[T]
+	msgCh    chan roomMessage[T]
+	history  *history[T]
+	policy   *policy
+	counters roomCounters
+
+	// Persisting is only started when the store is defined
+	persistCh     chan Message[T]
+	persistDoneCh chan empty
 }
 
 func New[T any](name string, cfg *Config[T]) *Room[T] {
+	return newRoom(name, cfg, nil)
+}
+
+func newRoom[T any](name string, cfg *Config[T], findRoom func(name string) (*Room[T], bool)) *Room[T] {
 	if cfg == nil {
 		cfg = NewRoomConfig[T]()
 	}
+	// Ignore the error, as the ID is only used to identify the messages published to the broker by this room
+	id, _ := createID()
+
 	r := &Room[T]{
-		name: name,
-		cfg:  cfg,
+		id:     id,
+		name:   name,
+		cfg:    cfg,
+		logger: cfg.Logger.With(slog.String("room", name)),
 
+		unsubscribe: nil,
+		findRoom:    findRoom,
+
+		doneCh:       make(chan empty),
 		closeCh:      make(chan roomClose),
 		registerCh:   make(chan roomRegistration[T]),
 		unregisterCh: make(chan roomRegistration[T]),
+		clientsCh:    make(chan roomClients[T]),
+
+		subscriptionCh: make(chan roomSubscription[T]),
 
-		clients: map[*Client[T]]empty{},
+		clients: map[*Client[T]]*subscription[T]{},
 
-		msgCh: make(chan roomMessage[T]),
+		msgCh:   make(chan roomMessage[T]),
+		history: newHistory[T](cfg.HistorySize, cfg.HistoryMaxAge),
+		policy:  newPolicy(cfg),
 	}
 
+	// The room still works without the previous messages
+	if err := r.restore(); err != nil {
+		r.logger.Warn("unable to restore the room history", slog.Any("error", err))
+	}
+
+	activeRoomsGauge.Inc()
+
+	r.startPersisting()
 	go r.start()
 
+	// The room still works for the clients of this node
+	if err := r.subscribe(); err != nil {
+		r.logger.Warn("unable to subscribe to the broker", slog.Any("error", err))
+	}
+
 	return r
 }
 
 func (r *Room[T]) start() {
+	// Requests made after the event loop has stopped are rejected with ErrRoomClosed,
+	// as the request channels are never closed
+	defer close(r.doneCh)
+
 handler:
 	for {
 		select {
 		case rc := <-r.closeCh:
 			r.closed.Store(true)
-			r.drainClose()
+			if r.unsubscribe != nil {
+				r.unsubscribe()
+			}
 			r.clientsClose()
 			r.storeSize()
+			r.stopPersisting()
+			activeRoomsGauge.Dec()
+
+			if r.cfg.OnClose != nil {
+				r.cfg.OnClose(r)
+			}
 
 			rc.ack.done(nil)
 			break handler
 		case rr := <-r.registerCh:
-			r.clients[rr.client] = empty{}
-			r.storeSize()
+			_, isRegistered := r.clients[rr.client]
+			if !isRegistered {
+				if err := r.policy.check(rr.client.ID(), rr.password, len(r.clients)); err != nil {
+					rr.ack.done(err)
+					continue
+				}
+			}
+
+			if !isRegistered {
+				r.clients[rr.client] = newSubscription[T]()
+				r.storeSize()
+
+				if r.cfg.OnRegister != nil {
+					r.cfg.OnRegister(r, rr.client)
+				}
+			}
 
 			rr.ack.done(nil)
 		case rr := <-r.unregisterCh:
-			delete(r.clients, rr.client)
-			r.storeSize()
-
+			r.removeClient(rr.client)
 			rr.ack.done(nil)
-		case rm := <-r.msgCh:
+		case rc := <-r.clientsCh:
+			clients := make([]*Client[T], 0, len(r.clients))
 			for client := range r.clients {
+				clients = append(clients, client)
+			}
+			rc.clientsCh <- clients
+		case rs := <-r.subscriptionCh:
+			sub, ok := r.clients[rs.client]
+			if !ok {
+				rs.ack.done(ErrRoomClientNotRegistered)
+				continue
+			}
+
+			rs.update(sub)
+			rs.ack.done(nil)
+		case rm := <-r.msgCh:
+			startedAt := time.Now()
+			for client, sub := range r.clients {
 				if rm.sender == client {
 					continue
 				}
+				if _, ok := rm.exclude[client.ID()]; ok {
+					continue
+				}
+				if _, ok := rm.seen[client.ID()]; ok {
+					continue
+				}
+				if !sub.accepts(rm.msg) {
+					rm.report.Filtered++
+					continue
+				}
+				if rm.seen != nil {
+					rm.seen[client.ID()] = empty{}
+				}
+
+				var err error
+				if rm.msg.Volatile {
+					err = client.TrySend(rm.msg.Data)
+				} else {
+					err = client.Send(rm.msg.Data)
+				}
+				if errors.Is(err, ErrClientBusy) {
+					r.counters.dropped.Add(1)
+					rm.report.Dropped++
+					continue
+				}
+				if err != nil {
+					r.counters.dropped.Add(1)
+					rm.report.Failed[client.ID()] = err
+					if errors.Is(err, ErrClientClosed) {
+						r.removeClient(client)
+					}
+					continue
+				}
+				r.counters.msgsOut.Add(1)
+				rm.report.Delivered++
+			}
+			r.counters.msgsIn.Add(1)
+			roomMessagesTotal.Inc()
+			roomFanoutHist.Observe(time.Since(startedAt).Seconds())
+
+			// Volatile messages are transient e.g. typing indicators, therefore they're not kept
+			if !rm.msg.Volatile && r.keep(rm.msg) {
+				r.history.add(rm.msg)
+				r.enqueuePersist(rm.msg)
+			}
 
-				// Ignore the error currently
-				client.Send(rm.msg)
+			if r.cfg.OnMessage != nil {
+				r.cfg.OnMessage(r, rm.msg)
 			}
 			rm.ack.done(nil)
 		}
 	}
 }
 
+// keep returns whether the message is kept in the history and persisted to the store
+func (r *Room[T]) keep(msg Message[T]) bool {
+	return r.cfg.HistoryFilter == nil || r.cfg.HistoryFilter(msg.Data)
+}
+
+// removeClient removes the client from the room. It must only be called from the room's event loop
+func (r *Room[T]) removeClient(client *Client[T]) {
+	if _, ok := r.clients[client]; !ok {
+		return
+	}
+
+	delete(r.clients, client)
+	r.storeSize()
+
+	if r.cfg.OnUnregister != nil {
+		r.cfg.OnUnregister(r, client)
+	}
+}
+
 func (r *Room[T]) Name() string {
 	return r.name
 }
@@ -121,6 +314,22 @@ func (r *Room[T]) storeSize() {
 }
 
 func (r *Room[T]) Register(client *Client[T]) error {
+	return r.RegisterContext(context.Background(), client)
+}
+
+// RegisterContext registers the client, aborting when the context is cancelled
+func (r *Room[T]) RegisterContext(ctx context.Context, client *Client[T]) error {
+	return r.RegisterWithPasswordContext(ctx, client, "")
+}
+
+// RegisterWithPassword registers the client using the password, which is required when the room is password-protected.
+// When the client isn't allowed to join the room, then ErrRoomFull, ErrRoomForbidden or ErrRoomPassword is returned
+func (r *Room[T]) RegisterWithPassword(client *Client[T], password string) error {
+	return r.RegisterWithPasswordContext(context.Background(), client, password)
+}
+
+// RegisterWithPasswordContext registers the client using the password, aborting when the context is cancelled
+func (r *Room[T]) RegisterWithPasswordContext(ctx context.Context, client *Client[T], password string) error {
 	if client == nil {
 		return ErrRoomClientNil
 	}
@@ -129,15 +338,22 @@ func (r *Room[T]) Register(client *Client[T]) error {
 	}
 
 	req := roomRegistration[T]{
-		client: client,
-		ack:    newACK(),
+		client:   client,
+		password: password,
+		ack:      newACK(),
 	}
-	r.registerCh <- req
-
-	return req.ack.wait()
+	if err := request(ctx, r.doneCh, r.registerCh, req); err != nil {
+		return err
+	}
+	return req.ack.wait(ctx)
 }
 
 func (r *Room[T]) Unregister(client *Client[T]) error {
+	return r.UnregisterContext(context.Background(), client)
+}
+
+// UnregisterContext unregisters the client, aborting when the context is cancelled
+func (r *Room[T]) UnregisterContext(ctx context.Context, client *Client[T]) error {
 	if client == nil {
 		return ErrRoomClientNil
 	}
@@ -149,92 +365,230 @@ func (r *Room[T]) Unregister(client *Client[T]) error {
 		client: client,
 		ack:    newACK(),
 	}
-	r.unregisterCh <- req
+	if err := request(ctx, r.doneCh, r.unregisterCh, req); err != nil {
+		return err
+	}
+	return req.ack.wait(ctx)
+}
+
+// Clients returns a snapshot of the clients currently registered in the room
+func (r *Room[T]) Clients() ([]*Client[T], error) {
+	return r.ClientsContext(context.Background())
+}
+
+// ClientsContext returns a snapshot of the clients currently registered in the room, aborting when the context is cancelled
+func (r *Room[T]) ClientsContext(ctx context.Context) ([]*Client[T], error) {
+	if r.closed.Load() {
+		return nil, ErrRoomClosed
+	}
 
-	return req.ack.wait()
+	req := roomClients[T]{
+		clientsCh: make(chan []*Client[T], 1),
+	}
+	if err := request(ctx, r.doneCh, r.clientsCh, req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case clients := <-req.clientsCh:
+		return clients, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
 func (r *Room[T]) Send(sender *Client[T], msg T) error {
-	return r.send(sender, msg)
+	return r.SendContext(context.Background(), sender, msg)
+}
+
+// SendContext sends the message to all clients except the sender, aborting when the context is cancelled
+func (r *Room[T]) SendContext(ctx context.Context, sender *Client[T], msg T) error {
+	_, err := r.send(ctx, sender, msg, sendOptions{})
+	return err
+}
+
+// SendVolatile sends the message to all clients except the sender, where the message is dropped for the clients
+// not ready to receive it instead of blocking the room. Volatile messages are not kept in the history
+func (r *Room[T]) SendVolatile(sender *Client[T], msg T) error {
+	return r.SendVolatileContext(context.Background(), sender, msg)
+}
+
+// SendVolatileContext sends the volatile message to all clients except the sender, aborting when the context
+// is cancelled
+func (r *Room[T]) SendVolatileContext(ctx context.Context, sender *Client[T], msg T) error {
+	_, err := r.send(ctx, sender, msg, sendOptions{volatile: true})
+	return err
+}
+
+// SendWithReport sends the message to all clients except the sender, returning the delivery report
+func (r *Room[T]) SendWithReport(sender *Client[T], msg T) (DeliveryReport, error) {
+	return r.SendWithReportContext(context.Background(), sender, msg)
+}
+
+// SendWithReportContext sends the message to all clients except the sender, returning the delivery report,
+// aborting when the context is cancelled
+func (r *Room[T]) SendWithReportContext(ctx context.Context, sender *Client[T], msg T) (DeliveryReport, error) {
+	return r.send(ctx, sender, msg, sendOptions{})
 }
 
 func (r *Room[T]) Broadcast(msg T) error {
-	return r.send(nil, msg)
+	return r.BroadcastContext(context.Background(), msg)
+}
+
+// BroadcastContext sends the message to all clients, aborting when the context is cancelled
+func (r *Room[T]) BroadcastContext(ctx context.Context, msg T) error {
+	_, err := r.send(ctx, nil, msg, sendOptions{})
+	return err
+}
+
+// BroadcastVolatile sends the message to all clients, where the message is dropped for the clients
+// not ready to receive it instead of blocking the room. Volatile messages are not kept in the history
+func (r *Room[T]) BroadcastVolatile(msg T) error {
+	return r.BroadcastVolatileContext(context.Background(), msg)
+}
+
+// BroadcastVolatileContext sends the volatile message to all clients, aborting when the context is cancelled
+func (r *Room[T]) BroadcastVolatileContext(ctx context.Context, msg T) error {
+	_, err := r.send(ctx, nil, msg, sendOptions{volatile: true})
+	return err
+}
+
+// BroadcastWithReport sends the message to all clients, returning the delivery report
+func (r *Room[T]) BroadcastWithReport(msg T) (DeliveryReport, error) {
+	return r.BroadcastWithReportContext(context.Background(), msg)
+}
+
+// BroadcastWithReportContext sends the message to all clients, returning the delivery report, aborting when
+// the context is cancelled
+func (r *Room[T]) BroadcastWithReportContext(ctx context.Context, msg T) (DeliveryReport, error) {
+	return r.send(ctx, nil, msg, sendOptions{})
+}
+
+// History returns the page of messages from the room's history in chronological order.
+// When the history is disabled, then nil is returned, otherwise a non-nil slice is returned,
+// which is empty when no message is matched e.g. the message with the query's BeforeID has been removed
+func (r *Room[T]) History(q HistoryQuery) []Message[T] {
+	return r.history.query(q)
+}
+
+// sendOptions defines how a message is sent through the room
+type sendOptions struct {
+	volatile bool
+
+	// See roomMessage.exclude and roomMessage.seen
+	exclude map[string]empty
+	seen    map[string]empty
+
+	// Names of the rooms the message was already sent through, where the other nodes skip the clients of
+	// these rooms, as the seen set only has the clients of this node
+	sentRooms []string
+}
+
+func (r *Room[T]) send(ctx context.Context, sender *Client[T], data T, opts sendOptions) (DeliveryReport, error) {
+	id, err := createID()
+	if err != nil {
+		return DeliveryReport{}, err
+	}
+
+	msg := Message[T]{
+		ID:       id,
+		SenderID: "",
+		Data:     data,
+		Topics:   nil,
+		Time:     time.Now(),
+		Volatile: opts.volatile,
+	}
+	if sender != nil {
+		msg.SenderID = sender.ID()
+	}
+	if r.cfg.Topics != nil {
+		msg.Topics = r.cfg.Topics(data)
+	}
+
+	_, span := r.cfg.Tracer.Start(ctx, "room.send")
+	defer span.End()
+	span.SetAttribute("room", r.name)
+	span.SetAttribute("message_id", msg.ID)
+
+	report, err := r.deliver(ctx, sender, msg, opts.exclude, opts.seen)
+	if err == nil {
+		err = r.publish(msg, opts.exclude, opts.sentRooms)
+	}
+	span.SetAttribute("delivered", report.Delivered)
+	span.SetAttribute("filtered", report.Filtered)
+	span.SetAttribute("dropped", report.Dropped)
+	span.SetAttribute("failed", len(report.Failed))
+	span.SetError(err)
+	return report, err
 }
 
-func (r *Room[T]) send(sender *Client[T], msg T) error {
+func (r *Room[T]) deliver(ctx context.Context, sender *Client[T], msg Message[T], exclude, seen map[string]empty) (DeliveryReport, error) {
 	if r.closed.Load() {
-		return ErrRoomClosed
+		return DeliveryReport{}, ErrRoomClosed
 	}
 
 	req := roomMessage[T]{
-		msg:    msg,
-		sender: sender,
-		ack:    newACK(),
+		msg:     msg,
+		sender:  sender,
+		exclude: exclude,
+		seen:    seen,
+		ack:     newACK(),
+		report: &DeliveryReport{
+			Delivered: 0,
+			Filtered:  0,
+			Dropped:   0,
+			Failed:    map[string]error{},
+		},
 	}
-	r.msgCh <- req
-
-	return req.ack.wait()
+	if err := request(ctx, r.doneCh, r.msgCh, req); err != nil {
+		return DeliveryReport{}, err
+	}
+	if err := req.ack.wait(ctx); err != nil {
+		return DeliveryReport{}, err
+	}
+	return *req.report, nil
 }
 
+// Close closes the room and its clients. Only the first call closes the room, where any subsequent calls
+// return ErrRoomClosed
 func (r *Room[T]) Close() error {
-	if r.closed.Load() {
+	if !r.closing.CompareAndSwap(false, true) {
 		return ErrRoomClosed
 	}
 
+	// Wait for all the clients to close or on timeout
+	ctx, cancel := context.WithTimeout(context.Background(), r.cfg.CloseTimeout)
+	defer cancel()
+
 	req := roomClose{
 		ack: newACK(),
 	}
-	r.closeCh <- req
-
-	// Wait for all the clients to close or on timeout
-	select {
-	case err := <-req.ack.waiter():
-		req.ack.close()
+	if err := request(ctx, r.doneCh, r.closeCh, req); err != nil {
+		// The request was never received by the room's event loop, so allow closing to be retried
+		r.closing.Store(false)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return ErrRoomCloseTimeout
+		}
 		return err
-	case <-time.After(r.cfg.CloseTimeout):
+	}
+
+	err := req.ack.wait(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
 		return ErrRoomCloseTimeout
 	}
+	return err
 }
 
-func (r *Room[T]) drainClose() {
-	ticker := time.NewTicker(256 * time.Millisecond)
-	defer ticker.Stop()
-
-	hasBeenCalled := false
-drainer:
-	for {
-		select {
-		case rc := <-r.closeCh:
-			hasBeenCalled = true
-			rc.ack.done(ErrClientClosed)
-		case rr := <-r.registerCh:
-			hasBeenCalled = true
-			rr.ack.done(ErrClientClosed)
-		case rr := <-r.unregisterCh:
-			hasBeenCalled = true
-			rr.ack.done(ErrClientClosed)
-		case rm := <-r.msgCh:
-			hasBeenCalled = true
-			rm.ack.done(ErrClientClosed)
-		default:
-			select {
-			case <-ticker.C:
-				// If one of the channels has been called, then wait again for the next tick to ensure
-				// no channels have been called, and they have all drained
-				if hasBeenCalled {
-					hasBeenCalled = false
-					continue drainer
-				}
-
-				close(r.closeCh)
-				close(r.registerCh)
-				close(r.unregisterCh)
-				close(r.msgCh)
-				break drainer
-			default:
-			}
-		}
+// request sends the request to the room's event loop, aborting when the context is cancelled or
+// the event loop has stopped
+func request[R any](ctx context.Context, doneCh <-chan empty, ch chan<- R, req R) error {
+	select {
+	case ch <- req:
+		return nil
+	case <-doneCh:
+		return ErrRoomClosed
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
@@ -1,11 +1,15 @@
 package room
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+	"github.com/softwarespot/chatterbox/pkg/trace"
 )
 
 func Test_NewRoom(t *testing.T) {
@@ -61,3 +65,240 @@ func Test_NewRoom(t *testing.T) {
 
 	time.Sleep(1 * time.Millisecond)
 }
+
+func Test_RoomContext(t *testing.T) {
+	r := New[string]("root", nil)
+
+	c1, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+	c2, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+
+	testhelpers.AssertNoError(t, r.Register(c1))
+
+	// Block the room's event loop, as client 1 isn't reading its messages
+	go r.Broadcast("blocking")
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	testhelpers.AssertEqual(t, r.RegisterContext(ctx, c2), context.DeadlineExceeded)
+	testhelpers.AssertEqual(t, r.BroadcastContext(ctx, "cancelled"), context.DeadlineExceeded)
+	testhelpers.AssertEqual(t, r.SendContext(ctx, c1, "cancelled"), context.DeadlineExceeded)
+
+	// Unblock the room's event loop
+	testhelpers.AssertEqual(t, <-c1.Messages(), "blocking")
+	testhelpers.AssertNoError(t, r.RegisterContext(context.Background(), c2))
+	testhelpers.AssertNoError(t, r.Close())
+}
+
+func Test_RoomDeliveryReport(t *testing.T) {
+	r := New[string]("root", nil)
+	defer r.Close()
+
+	c1, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+	c2, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+	c3, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+
+	testhelpers.AssertNoError(t, r.Register(c1))
+	testhelpers.AssertNoError(t, r.Register(c2))
+	testhelpers.AssertNoError(t, r.Register(c3))
+	testhelpers.AssertNoError(t, c3.Close())
+
+	go func() {
+		for range c2.Messages() {
+		}
+	}()
+
+	report, err := r.SendWithReport(c1, "hello")
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, report.Delivered, 1)
+	testhelpers.AssertEqual(t, report.Failed, map[string]error{c3.ID(): ErrClientClosed})
+
+	// The closed client has been automatically unregistered
+	testhelpers.AssertEqual(t, r.Size(), 2)
+
+	go func() {
+		for range c1.Messages() {
+		}
+	}()
+
+	report, err = r.BroadcastWithReport("hello")
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, report.Delivered, 2)
+	testhelpers.AssertEqual(t, report.Failed, map[string]error{})
+}
+
+func Test_RoomCloseStress(t *testing.T) {
+	for range 20 {
+		r := New[int]("root", nil)
+
+		var wg sync.WaitGroup
+		errCh := make(chan error, 1024)
+		for i := range 16 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				c, err := NewClient[int]()
+				if err != nil {
+					errCh <- err
+					return
+				}
+				go func() {
+					for range c.Messages() {
+					}
+				}()
+
+				ops := []func() error{
+					func() error { return r.Register(c) },
+					func() error { return r.Send(c, i) },
+					func() error { return r.Broadcast(i) },
+					func() error {
+						_, err := r.Clients()
+						return err
+					},
+					func() error { return r.Unregister(c) },
+				}
+				for j := range 50 {
+					if err := ops[j%len(ops)](); err != nil && !errors.Is(err, ErrRoomClosed) {
+						errCh <- err
+						return
+					}
+				}
+			}()
+		}
+
+		closeErrs := make(chan error, 4)
+		for range cap(closeErrs) {
+			go func() {
+				closeErrs <- r.Close()
+			}()
+		}
+
+		closedCount := 0
+		for range cap(closeErrs) {
+			err := <-closeErrs
+			if err == nil {
+				closedCount++
+				continue
+			}
+			testhelpers.AssertEqual(t, err, ErrRoomClosed)
+		}
+		testhelpers.AssertEqual(t, closedCount, 1)
+
+		wg.Wait()
+		close(errCh)
+		for err := range errCh {
+			testhelpers.AssertNoError(t, err)
+		}
+
+		// Late callers are deterministically rejected
+		c, err := NewClient[int]()
+		testhelpers.AssertNoError(t, err)
+		testhelpers.AssertEqual(t, r.Register(c), ErrRoomClosed)
+		testhelpers.AssertEqual(t, r.Broadcast(0), ErrRoomClosed)
+		testhelpers.AssertEqual(t, r.Close(), ErrRoomClosed)
+		testhelpers.AssertEqual(t, r.Size(), 0)
+	}
+}
+
+func Test_RoomVolatile(t *testing.T) {
+	cfg := NewRoomConfig[string]()
+	cfg.HistorySize = 10
+
+	r := New("root", cfg)
+	defer r.Close()
+
+	c1, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertNoError(t, r.Register(c1))
+
+	// The client isn't receiving, so the volatile message is dropped instead of blocking the room
+	report, err := r.send(context.Background(), nil, "typing", sendOptions{volatile: true})
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, report.Dropped, 1)
+	testhelpers.AssertEqual(t, report.Delivered, 0)
+	testhelpers.AssertEqual(t, len(r.History(HistoryQuery{})), 0)
+
+	receivedCh := make(chan string, 1)
+	go func() {
+		for msg := range c1.Messages() {
+			select {
+			case receivedCh <- msg:
+			default:
+			}
+		}
+	}()
+
+	// Retry until the client is waiting to receive
+	go func() {
+		for {
+			if err := r.BroadcastVolatile("typing"); err != nil {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	testhelpers.AssertEqual(t, <-receivedCh, "typing")
+	testhelpers.AssertEqual(t, len(r.History(HistoryQuery{})), 0)
+}
+
+func Test_RoomVolatileBufferedClient(t *testing.T) {
+	r := New[string]("root", nil)
+	defer r.Close()
+
+	c1, err := NewBufferedClient[string](1)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertNoError(t, r.Register(c1))
+
+	// The client isn't receiving, but the volatile message is buffered
+	report, err := r.send(context.Background(), nil, "typing", sendOptions{volatile: true})
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, report.Delivered, 1)
+	testhelpers.AssertEqual(t, report.Dropped, 0)
+
+	// The buffer is full, so the volatile message is dropped
+	report, err = r.send(context.Background(), nil, "typing", sendOptions{volatile: true})
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, report.Delivered, 0)
+	testhelpers.AssertEqual(t, report.Dropped, 1)
+
+	testhelpers.AssertEqual(t, <-c1.Messages(), "typing")
+}
+
+func Test_RoomTracer(t *testing.T) {
+	exp := trace.NewInMemoryExporter()
+	tracer := trace.NewTracer(exp)
+
+	cfg := NewRoomConfig[string]()
+	cfg.Tracer = tracer
+
+	r := New("root", cfg)
+	defer r.Close()
+
+	c, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertNoError(t, r.Register(c))
+
+	go func() {
+		for range c.Messages() {
+		}
+	}()
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	testhelpers.AssertNoError(t, r.SendContext(ctx, nil, "hello"))
+	parent.End()
+
+	spans := exp.Spans()
+	testhelpers.AssertEqual(t, len(spans), 2)
+	testhelpers.AssertEqual(t, spans[0].Name, "room.send")
+	testhelpers.AssertEqual(t, spans[0].TraceID, parent.SpanContext().TraceID)
+	testhelpers.AssertEqual(t, spans[0].ParentSpanID, parent.SpanContext().SpanID)
+	testhelpers.AssertEqual(t, spans[0].Attributes["room"], any("root"))
+	testhelpers.AssertEqual(t, spans[0].Attributes["delivered"], any(1))
+}
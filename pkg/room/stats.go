@@ -0,0 +1,94 @@
+package room
+
+import (
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// RoomStats defines the statistics of a room
+type RoomStats struct {
+	Name    string `json:"name"`
+	Clients int    `json:"clients"`
+
+	// Number of messages sent through the room
+	MessagesIn uint64 `json:"messagesIn"`
+
+	// Number of messages delivered to the room's clients
+	MessagesOut uint64 `json:"messagesOut"`
+
+	// Number of messages which failed to be delivered to the room's clients
+	Dropped uint64 `json:"dropped"`
+}
+
+// ManagerStats defines the statistics of a manager and its rooms
+type ManagerStats struct {
+	RoomCount int `json:"roomCount"`
+
+	// Number of clients added to the manager
+	ClientCount int `json:"clientCount"`
+
+	// Number of clients registered across all rooms, where a client registered in multiple rooms is counted once per room
+	MemberCount int `json:"memberCount"`
+
+	MessagesIn  uint64      `json:"messagesIn"`
+	MessagesOut uint64      `json:"messagesOut"`
+	Dropped     uint64      `json:"dropped"`
+	Rooms       []RoomStats `json:"rooms"`
+}
+
+type roomCounters struct {
+	msgsIn  atomic.Uint64
+	msgsOut atomic.Uint64
+	dropped atomic.Uint64
+}
+
+// Stats returns the statistics of the room
+func (r *Room[T]) Stats() RoomStats {
+	return RoomStats{
+		Name:        r.name,
+		Clients:     r.Size(),
+		MessagesIn:  r.counters.msgsIn.Load(),
+		MessagesOut: r.counters.msgsOut.Load(),
+		Dropped:     r.counters.dropped.Load(),
+	}
+}
+
+// Rooms returns the rooms of the manager ordered by name
+func (m *Manager[T]) Rooms() []*Room[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rooms := make([]*Room[T], 0, len(m.rooms))
+	for _, room := range m.rooms {
+		rooms = append(rooms, room)
+	}
+	slices.SortFunc(rooms, func(a, b *Room[T]) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return rooms
+}
+
+// Stats returns the statistics of the manager and its rooms
+func (m *Manager[T]) Stats() ManagerStats {
+	rooms := m.Rooms()
+
+	m.mu.Lock()
+	clientCount := len(m.clients)
+	m.mu.Unlock()
+
+	stats := ManagerStats{
+		RoomCount:   len(rooms),
+		ClientCount: clientCount,
+		Rooms:       make([]RoomStats, 0, len(rooms)),
+	}
+	for _, room := range rooms {
+		rs := room.Stats()
+		stats.MemberCount += rs.Clients
+		stats.MessagesIn += rs.MessagesIn
+		stats.MessagesOut += rs.MessagesOut
+		stats.Dropped += rs.Dropped
+		stats.Rooms = append(stats.Rooms, rs)
+	}
+	return stats
+}
@@ -0,0 +1,45 @@
+package room
+
+import (
+	"testing"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_ManagerStats(t *testing.T) {
+	m := NewManager[string](nil)
+	defer m.Close()
+
+	r1 := m.Load("b", nil)
+	m.Load("a", nil)
+
+	c1, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+	c2, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+
+	testhelpers.AssertNoError(t, m.AddClient(c1))
+	testhelpers.AssertNoError(t, r1.Register(c1))
+	testhelpers.AssertNoError(t, r1.Register(c2))
+	testhelpers.AssertNoError(t, c2.Close())
+
+	go func() {
+		for range c1.Messages() {
+		}
+	}()
+	testhelpers.AssertNoError(t, r1.Broadcast("hello"))
+
+	stats := m.Stats()
+	testhelpers.AssertEqual(t, stats, ManagerStats{
+		RoomCount:   2,
+		ClientCount: 1,
+		MemberCount: 1,
+		MessagesIn:  1,
+		MessagesOut: 1,
+		Dropped:     1,
+		Rooms: []RoomStats{
+			{Name: "a"},
+			{Name: "b", Clients: 1, MessagesIn: 1, MessagesOut: 1, Dropped: 1},
+		},
+	})
+}
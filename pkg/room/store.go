@@ -0,0 +1,108 @@
+package room
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/softwarespot/chatterbox/pkg/store"
+)
+
+// Maximum number of messages queued to be persisted, where the room's event loop waits when the queue is full
+const persistQueueSize = 256
+
+// startPersisting starts the goroutine which persists the messages to the store, so the room's event loop
+// isn't blocked by the store's I/O
+func (r *Room[T]) startPersisting() {
+	if r.cfg.Store == nil {
+		return
+	}
+
+	r.persistCh = make(chan Message[T], persistQueueSize)
+	r.persistDoneCh = make(chan empty)
+	go func() {
+		defer close(r.persistDoneCh)
+
+		for msg := range r.persistCh {
+			if err := r.persist(msg); err != nil {
+				r.logger.Error("unable to persist the message", slog.String("message_id", msg.ID), slog.Any("error", err))
+			}
+		}
+	}()
+}
+
+// enqueuePersist queues the message to be persisted. It must only be called from the room's event loop
+func (r *Room[T]) enqueuePersist(msg Message[T]) {
+	if r.persistCh == nil {
+		return
+	}
+	r.persistCh <- msg
+}
+
+// stopPersisting waits for the queued messages to be persisted. It must only be called from the room's event loop
+func (r *Room[T]) stopPersisting() {
+	if r.persistCh == nil {
+		return
+	}
+	close(r.persistCh)
+	<-r.persistDoneCh
+}
+
+func (r *Room[T]) persist(msg Message[T]) error {
+	if r.cfg.Store == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(msg.Data)
+	if err != nil {
+		return fmt.Errorf("room: encoding the message: %w", err)
+	}
+
+	err = r.cfg.Store.Append(store.Message{
+		ID:       msg.ID,
+		Room:     r.name,
+		SenderID: msg.SenderID,
+		Data:     data,
+		Topics:   msg.Topics,
+		Time:     msg.Time,
+	})
+	if err != nil {
+		return fmt.Errorf("room: persisting the message: %w", err)
+	}
+	return nil
+}
+
+// restore loads the most recent persisted messages into the room's history
+func (r *Room[T]) restore() error {
+	if r.cfg.Store == nil || !r.history.enabled() {
+		return nil
+	}
+
+	var from time.Time
+	if r.cfg.HistoryMaxAge > 0 {
+		from = time.Now().Add(-r.cfg.HistoryMaxAge)
+	}
+
+	msgs, err := r.cfg.Store.Range(r.name, from, time.Time{}, r.cfg.HistorySize)
+	if err != nil {
+		return fmt.Errorf("room: restoring the messages: %w", err)
+	}
+
+	for _, msg := range msgs {
+		var data T
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			r.logger.Warn("invalid persisted message", slog.String("message_id", msg.ID), slog.Any("error", err))
+			continue
+		}
+
+		r.history.add(Message[T]{
+			ID:       msg.ID,
+			SenderID: msg.SenderID,
+			Data:     data,
+			Topics:   msg.Topics,
+			Time:     msg.Time,
+		})
+	}
+	return nil
+}
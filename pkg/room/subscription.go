@@ -0,0 +1,95 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+var ErrRoomClientNotRegistered = errors.New("room: client is not registered")
+
+// subscription defines which messages a client receives in a room.
+// When there are no topics and no filter, then the client receives all messages
+type subscription[T any] struct {
+	topics map[string]empty
+	filter func(msg Message[T]) bool
+}
+
+func newSubscription[T any]() *subscription[T] {
+	return &subscription[T]{
+		topics: map[string]empty{},
+		filter: nil,
+	}
+}
+
+// accepts returns true when the message has one of the subscribed topics or is accepted by the filter
+func (s *subscription[T]) accepts(msg Message[T]) bool {
+	if len(s.topics) == 0 && s.filter == nil {
+		return true
+	}
+	for _, topic := range msg.Topics {
+		if _, ok := s.topics[topic]; ok {
+			return true
+		}
+	}
+	return s.filter != nil && s.filter(msg)
+}
+
+type roomSubscription[T any] struct {
+	client *Client[T]
+	update func(sub *subscription[T])
+	ack    *ack
+}
+
+// Subscribe restricts the messages the client receives in the room to those with one of the topics,
+// where the topics of a message are defined by the room configuration's Topics function.
+// When no topics are defined, then the topics subscription is cleared
+func (r *Room[T]) Subscribe(client *Client[T], topics ...string) error {
+	return r.SubscribeContext(context.Background(), client, topics...)
+}
+
+// SubscribeContext restricts the messages the client receives in the room to those with one of the topics,
+// aborting when the context is cancelled
+func (r *Room[T]) SubscribeContext(ctx context.Context, client *Client[T], topics ...string) error {
+	topics = slices.Clone(topics)
+	return r.updateSubscription(ctx, client, func(sub *subscription[T]) {
+		clear(sub.topics)
+		for _, topic := range topics {
+			sub.topics[topic] = empty{}
+		}
+	})
+}
+
+// SetFilter restricts the messages the client receives in the room to those accepted by the filter,
+// in addition to the subscribed topics. The filter is called from the room's event loop.
+// When the filter is nil, then the filter is cleared
+func (r *Room[T]) SetFilter(client *Client[T], filter func(msg Message[T]) bool) error {
+	return r.SetFilterContext(context.Background(), client, filter)
+}
+
+// SetFilterContext restricts the messages the client receives in the room to those accepted by the filter,
+// aborting when the context is cancelled
+func (r *Room[T]) SetFilterContext(ctx context.Context, client *Client[T], filter func(msg Message[T]) bool) error {
+	return r.updateSubscription(ctx, client, func(sub *subscription[T]) {
+		sub.filter = filter
+	})
+}
+
+func (r *Room[T]) updateSubscription(ctx context.Context, client *Client[T], update func(sub *subscription[T])) error {
+	if client == nil {
+		return ErrRoomClientNil
+	}
+	if r.closed.Load() {
+		return ErrRoomClosed
+	}
+
+	req := roomSubscription[T]{
+		client: client,
+		update: update,
+		ack:    newACK(),
+	}
+	if err := request(ctx, r.doneCh, r.subscriptionCh, req); err != nil {
+		return err
+	}
+	return req.ack.wait(ctx)
+}
@@ -0,0 +1,77 @@
+package room
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_RoomSubscription(t *testing.T) {
+	cfg := NewRoomConfig[string]()
+	cfg.Topics = func(msg string) []string {
+		topic, _, ok := strings.Cut(msg, ":")
+		if !ok {
+			return nil
+		}
+		return []string{topic}
+	}
+
+	r := New("root", cfg)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	received := map[string][]string{}
+	newClient := func(name string) *Client[string] {
+		c, err := NewClient[string]()
+		testhelpers.AssertNoError(t, err)
+		testhelpers.AssertNoError(t, r.Register(c))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range c.Messages() {
+				mu.Lock()
+				received[name] = append(received[name], msg)
+				mu.Unlock()
+			}
+		}()
+		return c
+	}
+	alerts := newClient("alerts")
+	mentions := newClient("mentions")
+	newClient("all")
+
+	ctx := context.Background()
+	testhelpers.AssertNoError(t, r.SubscribeContext(ctx, alerts, "alerts"))
+	testhelpers.AssertNoError(t, r.SetFilterContext(ctx, mentions, func(msg Message[string]) bool {
+		return strings.Contains(msg.Data, "@mentions")
+	}))
+
+	unregistered, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, r.SubscribeContext(ctx, unregistered, "alerts"), ErrRoomClientNotRegistered)
+
+	report, err := r.BroadcastWithReportContext(ctx, "chat: hello")
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, report.Delivered, 1)
+	testhelpers.AssertEqual(t, report.Filtered, 2)
+
+	testhelpers.AssertNoError(t, r.Broadcast("alerts: disk full"))
+	testhelpers.AssertNoError(t, r.Broadcast("chat: hello @mentions"))
+
+	// Clearing the subscription receives all messages again
+	testhelpers.AssertNoError(t, r.Subscribe(alerts))
+	testhelpers.AssertNoError(t, r.Broadcast("chat: bye"))
+
+	testhelpers.AssertNoError(t, r.Close())
+	wg.Wait()
+
+	testhelpers.AssertEqual(t, received, map[string][]string{
+		"alerts":   {"alerts: disk full", "chat: bye"},
+		"mentions": {"chat: hello @mentions"},
+		"all":      {"chat: hello", "alerts: disk full", "chat: hello @mentions", "chat: bye"},
+	})
+}
@@ -0,0 +1,208 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+var ErrInvalidTopic = errors.New("room: invalid topic")
+
+// Hierarchical topics are tokens separated by a ".", e.g. "orders.eu.created".
+// Patterns support the wildcards "*", which matches exactly one token, and ">", which matches one or more
+// tokens and must be the last token e.g. "orders.*.created" or "orders.>"
+const (
+	topicSeparator     = "."
+	topicWildcardToken = "*"
+	topicWildcardTail  = ">"
+)
+
+func validTopic(topic string, isPattern bool) bool {
+	if topic == "" {
+		return false
+	}
+
+	tokens := strings.Split(topic, topicSeparator)
+	for i, token := range tokens {
+		switch {
+		case token == "":
+			return false
+		case token == topicWildcardToken:
+			if !isPattern {
+				return false
+			}
+		case token == topicWildcardTail:
+			if !isPattern || i != len(tokens)-1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchTopic returns true when the topic matches the pattern
+func matchTopic(pattern, topic string) bool {
+	patternTokens := strings.Split(pattern, topicSeparator)
+	topicTokens := strings.Split(topic, topicSeparator)
+
+	for i, token := range patternTokens {
+		if token == topicWildcardTail {
+			return len(topicTokens) > i
+		}
+		if i >= len(topicTokens) {
+			return false
+		}
+		if token != topicWildcardToken && token != topicTokens[i] {
+			return false
+		}
+	}
+	return len(patternTokens) == len(topicTokens)
+}
+
+// Subject of the broker, which the topic messages are published to
+const topicSubject = "topics"
+
+type topicMessage[T any] struct {
+	// Origin is the ID of the manager which published the message
+	Origin string `json:"origin"`
+	Topic  string `json:"topic"`
+	Data   T      `json:"data"`
+}
+
+// Subscribe registers the client in the room of the topic pattern, so the client receives the messages
+// published to the matching topics. The rooms of the topic patterns are separate from the rooms loaded by name
+func (m *Manager[T]) Subscribe(pattern string, client *Client[T]) error {
+	if !validTopic(pattern, true) {
+		return ErrInvalidTopic
+	}
+	return m.loadTopic(pattern).Register(client)
+}
+
+// Unsubscribe unregisters the client from the room of the topic pattern
+func (m *Manager[T]) Unsubscribe(pattern string, client *Client[T]) error {
+	if !validTopic(pattern, true) {
+		return ErrInvalidTopic
+	}
+
+	m.mu.Lock()
+	room, ok := m.topics[pattern]
+	m.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return room.Unregister(client)
+}
+
+// Publish sends the message to the clients subscribed to a pattern matching the topic, including the clients
+// connected to other nodes when the broker is defined. A client subscribed to multiple matching patterns
+// receives the message once
+func (m *Manager[T]) Publish(topic string, msg T) error {
+	return m.PublishContext(context.Background(), topic, msg)
+}
+
+// PublishContext sends the message to the clients subscribed to a pattern matching the topic, aborting when
+// the context is cancelled
+func (m *Manager[T]) PublishContext(ctx context.Context, topic string, msg T) error {
+	if !validTopic(topic, false) {
+		return ErrInvalidTopic
+	}
+
+	if err := m.deliverTopic(ctx, topic, msg); err != nil {
+		return err
+	}
+	return m.publishTopic(topic, msg)
+}
+
+func (m *Manager[T]) loadTopic(pattern string) *Room[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	room, ok := m.topics[pattern]
+	if !ok {
+		room = New(pattern, m.topicCfg)
+		m.topics[pattern] = room
+	}
+	return room
+}
+
+// deliverTopic sends the message through the rooms of the patterns matching the topic
+func (m *Manager[T]) deliverTopic(ctx context.Context, topic string, msg T) error {
+	m.mu.Lock()
+	var rooms []*Room[T]
+	for pattern, room := range m.topics {
+		if matchTopic(pattern, topic) {
+			rooms = append(rooms, room)
+		}
+	}
+	m.mu.Unlock()
+
+	seen := map[string]empty{}
+
+	var errs []error
+	for _, room := range rooms {
+		_, err := room.send(ctx, nil, msg, sendOptions{
+			seen: seen,
+		})
+		if err != nil && !errors.Is(err, ErrRoomClosed) {
+			errs = append(errs, fmt.Errorf("room: publishing to topic pattern %q: %w", room.Name(), err))
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Manager[T]) subscribeTopics() error {
+	if m.cfg.Broker == nil {
+		return nil
+	}
+
+	unsubscribe, err := m.cfg.Broker.Subscribe(topicSubject, m.onTopicMessage)
+	if err != nil {
+		return fmt.Errorf("room: subscribing to the broker: %w", err)
+	}
+	m.unsubscribe = unsubscribe
+	return nil
+}
+
+// publishTopic publishes the message to the broker, so it's sent to the subscribers connected to other nodes
+func (m *Manager[T]) publishTopic(topic string, msg T) error {
+	if m.cfg.Broker == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(topicMessage[T]{
+		Origin: m.id,
+		Topic:  topic,
+		Data:   msg,
+	})
+	if err != nil {
+		return fmt.Errorf("room: encoding the topic message: %w", err)
+	}
+	if err := m.cfg.Broker.Publish(topicSubject, data); err != nil {
+		return fmt.Errorf("room: publishing to the broker: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager[T]) onTopicMessage(data []byte) {
+	var tm topicMessage[T]
+	if err := json.Unmarshal(data, &tm); err != nil {
+		m.logger.Warn("invalid topic message", slog.Any("error", err))
+		return
+	}
+
+	// Ignore the messages published by this manager, as they have already been sent to the clients
+	if tm.Origin == m.id {
+		return
+	}
+
+	if err := m.deliverTopic(context.Background(), tm.Topic, tm.Data); err != nil {
+		m.logger.Debug("unable to deliver the topic message", slog.String("topic", tm.Topic), slog.Any("error", err))
+	}
+}
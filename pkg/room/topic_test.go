@@ -0,0 +1,125 @@
+package room
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/softwarespot/chatterbox/pkg/broker"
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_MatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{"orders.eu.created", "orders.eu.created", true},
+		{"orders.eu.created", "orders.us.created", false},
+		{"orders.*.created", "orders.eu.created", true},
+		{"orders.*.created", "orders.eu.deleted", false},
+		{"orders.*.created", "orders.eu.west.created", false},
+		{"orders.>", "orders.eu", true},
+		{"orders.>", "orders.eu.created", true},
+		{"orders.>", "orders", false},
+		{"*.>", "orders.eu.created", true},
+		{"orders.*", "orders.eu.created", false},
+	}
+	for _, tt := range tests {
+		testhelpers.AssertEqual(t, matchTopic(tt.pattern, tt.topic), tt.want)
+	}
+
+	testhelpers.AssertEqual(t, validTopic("orders.>", true), true)
+	testhelpers.AssertEqual(t, validTopic("orders.>.created", true), false)
+	testhelpers.AssertEqual(t, validTopic("orders..created", true), false)
+	testhelpers.AssertEqual(t, validTopic("orders.*.created", false), false)
+}
+
+func Test_ManagerPublish(t *testing.T) {
+	m := NewManager[string](nil)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	received := map[string][]string{}
+	newClient := func(name string, patterns ...string) *Client[string] {
+		c, err := NewClient[string]()
+		testhelpers.AssertNoError(t, err)
+		for _, pattern := range patterns {
+			testhelpers.AssertNoError(t, m.Subscribe(pattern, c))
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range c.Messages() {
+				mu.Lock()
+				received[name] = append(received[name], msg)
+				mu.Unlock()
+			}
+		}()
+		return c
+	}
+	newClient("created", "orders.*.created")
+	newClient("all", "orders.>", "orders.*.created")
+	eu := newClient("eu", "orders.eu.created")
+
+	testhelpers.AssertEqual(t, m.Subscribe("orders.>.created", eu), ErrInvalidTopic)
+	testhelpers.AssertEqual(t, m.Publish("orders.*", "invalid"), ErrInvalidTopic)
+
+	testhelpers.AssertNoError(t, m.Publish("orders.eu.created", "eu created"))
+	testhelpers.AssertNoError(t, m.Publish("orders.us.created", "us created"))
+	testhelpers.AssertNoError(t, m.Publish("orders.us.deleted", "us deleted"))
+
+	testhelpers.AssertNoError(t, m.Unsubscribe("orders.eu.created", eu))
+	testhelpers.AssertNoError(t, m.Publish("orders.eu.created", "eu created again"))
+
+	// The unsubscribed client isn't closed by the manager
+	testhelpers.AssertNoError(t, m.Close())
+	testhelpers.AssertNoError(t, eu.Close())
+	wg.Wait()
+
+	testhelpers.AssertEqual(t, received, map[string][]string{
+		"created": {"eu created", "us created", "eu created again"},
+		"all":     {"eu created", "us created", "us deleted", "eu created again"},
+		"eu":      {"eu created"},
+	})
+}
+
+func Test_ManagerTopicNamespace(t *testing.T) {
+	m := NewManager[string](nil)
+	defer m.Close()
+
+	// A room named like a topic pattern isn't subscribed to the topics
+	r := m.Load("orders.>", nil)
+	c, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertNoError(t, r.Register(c))
+
+	testhelpers.AssertNoError(t, m.Publish("orders.eu.created", "eu created"))
+	testhelpers.AssertEqual(t, len(m.Rooms()), 1)
+}
+
+func Test_ManagerPublishBroker(t *testing.T) {
+	b := broker.NewMemory()
+	defer b.Close()
+
+	cfg := NewRoomConfig[string]()
+	cfg.Broker = b
+
+	// Simulate two nodes, each with their own manager
+	nodeA := NewManager(cfg)
+	defer nodeA.Close()
+	nodeB := NewManager(cfg)
+	defer nodeB.Close()
+
+	c, err := NewClient[string]()
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertNoError(t, nodeB.Subscribe("orders.*.created", c))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- nodeA.Publish("orders.eu.created", "eu created")
+	}()
+	testhelpers.AssertEqual(t, receive(t, c), "eu created")
+	testhelpers.AssertNoError(t, <-errCh)
+}
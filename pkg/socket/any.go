@@ -0,0 +1,111 @@
+package socket
+
+import (
+	"log/slog"
+	"slices"
+)
+
+type anyListener struct {
+	id int
+	fn func(event string, args Args)
+
+	// Internal listeners e.g. logging the debug events are not removed by OffAny or OffAnyOutgoing
+	internal bool
+}
+
+// anyListeners are the listeners of all the events, which can be added and removed from any goroutine
+type anyListeners struct {
+	nextID    int
+	listeners []anyListener
+}
+
+func (l *anyListeners) add(fn func(event string, args Args), internal bool) int {
+	l.nextID++
+	l.listeners = append(l.listeners, anyListener{
+		id:       l.nextID,
+		fn:       fn,
+		internal: internal,
+	})
+	return l.nextID
+}
+
+func (l *anyListeners) remove(id int) {
+	l.listeners = slices.DeleteFunc(l.listeners, func(al anyListener) bool {
+		return al.id == id
+	})
+}
+
+// clear removes all the listeners, except for the internal listeners
+func (l *anyListeners) clear() {
+	l.listeners = slices.DeleteFunc(l.listeners, func(al anyListener) bool {
+		return !al.internal
+	})
+}
+
+// OnAny registers the function for all the events received, where the args don't include the "ack" function.
+// It returns a function to remove the listener
+func (s *Socket) OnAny(fn func(event string, args Args)) func() {
+	return s.onAny(&s.incomingListeners, fn, false)
+}
+
+// OffAny removes all the listeners registered with OnAny, where logging the debug events isn't affected
+func (s *Socket) OffAny() *Socket {
+	s.anyMu.Lock()
+	defer s.anyMu.Unlock()
+
+	s.incomingListeners.clear()
+	return s
+}
+
+// OnAnyOutgoing registers the function for all the events emitted, where the args don't include the "ack" function.
+// It returns a function to remove the listener
+func (s *Socket) OnAnyOutgoing(fn func(event string, args Args)) func() {
+	return s.onAny(&s.outgoingListeners, fn, false)
+}
+
+// OffAnyOutgoing removes all the listeners registered with OnAnyOutgoing, where logging the debug events isn't affected
+func (s *Socket) OffAnyOutgoing() *Socket {
+	s.anyMu.Lock()
+	defer s.anyMu.Unlock()
+
+	s.outgoingListeners.clear()
+	return s
+}
+
+func (s *Socket) onAny(l *anyListeners, fn func(event string, args Args), internal bool) func() {
+	s.anyMu.Lock()
+	defer s.anyMu.Unlock()
+
+	id := l.add(fn, internal)
+	return func() {
+		s.anyMu.Lock()
+		defer s.anyMu.Unlock()
+
+		l.remove(id)
+	}
+}
+
+func (s *Socket) emitAny(l *anyListeners, event string, args []any) {
+	s.anyMu.RLock()
+	listeners := slices.Clone(l.listeners)
+	s.anyMu.RUnlock()
+
+	for _, al := range listeners {
+		al.fn(event, args)
+	}
+}
+
+// logEvents logs all the events received and emitted at the debug level, when the debug mode of the configuration
+// is enabled. Only the number of args is logged, as they can contain credentials e.g. the password of a room
+func (s *Socket) logEvents() {
+	s.onAny(&s.incomingListeners, func(event string, args Args) {
+		if s.cfg.DebugEvents.Load() {
+			s.logger.Debug("received the event", slog.String("event", event), slog.Int("args", len(args)))
+		}
+	}, true)
+	s.onAny(&s.outgoingListeners, func(event string, args Args) {
+		if s.cfg.DebugEvents.Load() {
+			s.logger.Debug("emitted the event", slog.String("event", event), slog.Int("args", len(args)))
+		}
+	}, true)
+}
@@ -0,0 +1,110 @@
+package socket
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_SocketOnAny(t *testing.T) {
+	s, a := newTestSocket(t, nil)
+
+	type anyEvent struct {
+		event string
+		args  Args
+	}
+	incomingCh := make(chan anyEvent, 2)
+	removeFn := s.OnAny(func(event string, args Args) {
+		incomingCh <- anyEvent{event, args}
+	})
+
+	var outgoing []anyEvent
+	s.OnAnyOutgoing(func(event string, args Args) {
+		outgoing = append(outgoing, anyEvent{event, args})
+	})
+
+	handledCh := make(chan string, 2)
+	s.On("message", func(args ...any) {
+		msg, _ := ArgAt[string](args, 0)
+		handledCh <- msg
+	})
+	connectTestSocket(t, s, a)
+
+	a.receiveEvent("message", 1, "hello")
+	testhelpers.AssertEqual(t, <-incomingCh, anyEvent{"message", Args{"hello"}})
+	testhelpers.AssertEqual(t, <-handledCh, "hello")
+
+	removeFn()
+	a.receiveEvent("message", 0, "world")
+	testhelpers.AssertEqual(t, <-handledCh, "world")
+	testhelpers.AssertEqual(t, len(incomingCh), 0)
+
+	testhelpers.AssertNoError(t, s.Emit("message", "outgoing", func(...any) {}))
+	testhelpers.AssertEqual(t, outgoing, []anyEvent{{"message", Args{"outgoing"}}})
+
+	s.OffAnyOutgoing()
+	testhelpers.AssertNoError(t, s.Emit("message", "ignored"))
+	testhelpers.AssertEqual(t, len(outgoing), 1)
+
+	a.Close()
+}
+
+func Test_AnyListenersClear(t *testing.T) {
+	var l anyListeners
+	l.add(func(string, Args) {}, true)
+	l.add(func(string, Args) {}, false)
+
+	// Only the internal listeners e.g. logging the debug events are kept
+	l.clear()
+	testhelpers.AssertEqual(t, len(l.listeners), 1)
+	testhelpers.AssertEqual(t, l.listeners[0].internal, true)
+}
+
+// lockedBuffer is a buffer, which is safe to write to from the socket's goroutines
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func Test_SocketLogEvents(t *testing.T) {
+	var buf lockedBuffer
+	cfg := NewSocketConfig()
+	cfg.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	cfg.DebugEvents = new(atomic.Bool)
+	cfg.DebugEvents.Store(true)
+
+	s, a := newTestSocket(t, cfg)
+	handledCh := make(chan struct{})
+	s.On("join", func(args ...any) {
+		close(handledCh)
+	})
+	connectTestSocket(t, s, a)
+
+	a.receiveEvent("join", 0, "room", "secret-password")
+	<-handledCh
+
+	// The args aren't logged, as they can contain credentials
+	logs := buf.String()
+	testhelpers.AssertEqual(t, strings.Contains(logs, `level=DEBUG msg="received the event"`), true)
+	testhelpers.AssertEqual(t, strings.Contains(logs, "event=join args=2"), true)
+	testhelpers.AssertEqual(t, strings.Contains(logs, "secret-password"), false)
+
+	a.Close()
+}
@@ -0,0 +1,90 @@
+package socket
+
+import (
+	"encoding/base64"
+)
+
+// CapabilityBinary is the capability of sending binary args i.e. []byte as {"$binary": "<base64>"} objects
+// instead of base64 strings, so both sides receive them as binary data
+const CapabilityBinary = "binary"
+
+// Key of the object a binary arg is encoded as, when the binary capability was negotiated
+const binaryKey = "$binary"
+
+// encodeArgs encodes the binary args of a packet sent, when the binary capability was negotiated
+func (s *Socket) encodeArgs(args []any) []any {
+	if !s.protocol.HasCapability(CapabilityBinary) {
+		return args
+	}
+	return encodeBinaryArgs(args)
+}
+
+// decodeArgs decodes the binary args of a packet received, when the binary capability was negotiated
+func (s *Socket) decodeArgs(args []any) []any {
+	if !s.protocol.HasCapability(CapabilityBinary) {
+		return args
+	}
+	return decodeBinaryArgs(args)
+}
+
+// encodeBinaryArgs returns the args, where the binary args, including those nested in arrays and objects,
+// are encoded as {"$binary": "<base64>"} objects
+func encodeBinaryArgs(args []any) []any {
+	encoded := make([]any, len(args))
+	for i, arg := range args {
+		encoded[i] = encodeBinary(arg)
+	}
+	return encoded
+}
+
+func encodeBinary(v any) any {
+	switch v := v.(type) {
+	case []byte:
+		return map[string]any{
+			binaryKey: base64.StdEncoding.EncodeToString(v),
+		}
+	case []any:
+		return encodeBinaryArgs(v)
+	case map[string]any:
+		encoded := make(map[string]any, len(v))
+		for k, v := range v {
+			encoded[k] = encodeBinary(v)
+		}
+		return encoded
+	default:
+		return v
+	}
+}
+
+// decodeBinaryArgs returns the args, where the {"$binary": "<base64>"} objects, including those nested
+// in arrays and objects, are decoded to binary args
+func decodeBinaryArgs(args []any) []any {
+	decoded := make([]any, len(args))
+	for i, arg := range args {
+		decoded[i] = decodeBinary(arg)
+	}
+	return decoded
+}
+
+func decodeBinary(v any) any {
+	switch v := v.(type) {
+	case []any:
+		return decodeBinaryArgs(v)
+	case map[string]any:
+		if s, ok := v[binaryKey].(string); ok && len(v) == 1 {
+			// An invalid encoding is kept as is, as it's likely an object with the same key
+			if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+				return b
+			}
+			return v
+		}
+
+		decoded := make(map[string]any, len(v))
+		for k, v := range v {
+			decoded[k] = decodeBinary(v)
+		}
+		return decoded
+	default:
+		return v
+	}
+}
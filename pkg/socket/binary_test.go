@@ -0,0 +1,62 @@
+package socket
+
+import (
+	"testing"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_BinaryArgs(t *testing.T) {
+	args := []any{
+		[]byte("chatterbox"),
+		"text",
+		[]any{[]byte{0, 1, 2}},
+		map[string]any{
+			"data": []byte{255},
+		},
+	}
+
+	encoded := encodeBinaryArgs(args)
+	testhelpers.AssertEqual(t, encoded, []any{
+		map[string]any{binaryKey: "Y2hhdHRlcmJveA=="},
+		"text",
+		[]any{map[string]any{binaryKey: "AAEC"}},
+		map[string]any{
+			"data": map[string]any{binaryKey: "/w=="},
+		},
+	})
+	testhelpers.AssertEqual(t, decodeBinaryArgs(encoded), args)
+
+	// Objects which aren't an encoded binary arg are kept as is
+	other := []any{
+		map[string]any{binaryKey: 1},
+		map[string]any{binaryKey: "not base64!"},
+		map[string]any{binaryKey: "AAEC", "other": true},
+	}
+	testhelpers.AssertEqual(t, decodeBinaryArgs(other), other)
+}
+
+func Test_SocketBinaryArgs(t *testing.T) {
+	a := newTestAdapter()
+	s, err := newSocket(a, nil, Protocol{Version: 1, Capabilities: []string{CapabilityBinary}}, Handshake{})
+	testhelpers.AssertNoError(t, err)
+
+	s.On("upload", func(args ...any) {
+		if ackFn, ok := GetAckFunc(args); ok {
+			ackFn(args[0])
+		}
+	})
+	done := connectTestSocket(t, s, a)
+
+	a.receiveEvent("upload", 1, map[string]any{binaryKey: "AAEC"})
+	pkt := <-a.sent
+	testhelpers.AssertEqual(t, pkt.Type, "ack")
+	testhelpers.AssertEqual(t, pkt.Data["args"], any([]any{map[string]any{binaryKey: "AAEC"}}))
+
+	testhelpers.AssertNoError(t, s.Emit("download", []byte{0, 1, 2}))
+	pkt = <-a.sent
+	testhelpers.AssertEqual(t, pkt.Data["args"], any([]any{map[string]any{binaryKey: "AAEC"}}))
+
+	a.Close()
+	<-done
+}
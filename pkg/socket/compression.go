@@ -0,0 +1,44 @@
+package socket
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+)
+
+// CapabilityCompression is the capability of sending the packets of at least the configuration's
+// CompressionMinBytes as deflate compressed binary frames
+const CapabilityCompression = "compression"
+
+// deflate compresses the data, which is decompressed by the browser with DecompressionStream("deflate-raw")
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// inflate decompresses the data, where the decompressed size is limited, so a small frame can't expand
+// to an unbounded packet
+func inflate(data []byte, maxBytes int) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+
+	decompressed, err := io.ReadAll(io.LimitReader(r, int64(maxBytes)+1))
+	if err != nil {
+		return nil, fmt.Errorf("socket: decompressing the frame: %w", err)
+	}
+	if len(decompressed) > maxBytes {
+		return nil, ErrPacketTooLarge
+	}
+	return decompressed, nil
+}
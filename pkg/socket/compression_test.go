@@ -0,0 +1,72 @@
+package socket
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+	"golang.org/x/net/websocket"
+)
+
+func Test_Inflate(t *testing.T) {
+	data := []byte(strings.Repeat("chatterbox", 100))
+	compressed, err := deflate(data)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, len(compressed) < len(data), true)
+
+	decompressed, err := inflate(compressed, len(data))
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, decompressed, data)
+
+	// A frame can't expand past the limit
+	_, err = inflate(compressed, len(data)-1)
+	testhelpers.AssertEqual(t, errors.Is(err, ErrPacketTooLarge), true)
+
+	_, err = inflate([]byte("invalid"), len(data))
+	testhelpers.AssertError(t, err)
+}
+
+func Test_IOCompression(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.Capabilities = []string{CapabilityCompression}
+	cfg.CompressionMinBytes = 64
+
+	errCh := make(chan error, 1)
+	srv := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		errCh <- IO(conn, cfg, func(s *Socket) error {
+			s.On("echo", func(args ...any) {
+				if ackFn, ok := GetAckFunc(args); ok {
+					ackFn(args[0])
+				}
+			})
+			return nil
+		})
+	}))
+	defer srv.Close()
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/?capabilities=compression", "", srv.URL)
+	testhelpers.AssertNoError(t, err)
+
+	// A packet smaller than the minimum size is sent as a text frame
+	var text string
+	testhelpers.AssertNoError(t, websocket.Message.Receive(conn, &text))
+	testhelpers.AssertEqual(t, strings.Contains(text, `"connect"`), true)
+
+	// A compressed packet is received as a binary frame
+	msg := strings.Repeat("a", 128)
+	data, err := deflate([]byte(`{"type":"event","data":{"event":"echo","args":["` + msg + `"],"ackId":1}}`))
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertNoError(t, websocket.Message.Send(conn, data))
+
+	// A packet of at least the minimum size is sent as a compressed binary frame
+	var frame []byte
+	testhelpers.AssertNoError(t, websocket.Message.Receive(conn, &frame))
+	decompressed, err := inflate(frame, 1024)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, string(decompressed), `{"type":"ack","data":{"args":["`+msg+`"],"id":1}}`)
+
+	conn.Close()
+	testhelpers.AssertNoError(t, <-errCh)
+}
@@ -0,0 +1,150 @@
+package socket
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/softwarespot/chatterbox/pkg/trace"
+)
+
+// Config defines the configuration settings for a socket.
+type Config struct {
+	// Logger used by the socket, where the "socket_id" attribute is added. Default is slog.Default()
+	Logger *slog.Logger
+
+	// Tracer used to create spans around the event dispatch and emits. Default is nil i.e. tracing is disabled
+	Tracer *trace.Tracer
+
+	// Called when a handler panics, where the error is a *HandlerPanicError with the event name and stack.
+	// Default is nil i.e. the panic is logged
+	ErrorHandler func(s *Socket, err error)
+
+	// Called in order before the socket is connected, where the first error rejects the connection.
+	// Default is nil
+	Middlewares []Middleware
+
+	// Maximum duration to wait for the "auth" packet with the client's credentials, when the "Authorization"
+	// header isn't set. When zero, then the "auth" packet isn't waited for. Default is 0
+	AuthTimeout time.Duration
+
+	// Whether the handlers are dispatched on workers instead of the goroutine receiving the packets,
+	// so a slow handler doesn't block receiving e.g. acknowledgements. Default is false
+	AsyncDispatch bool
+
+	// Number of workers dispatching the handlers, when dispatched asynchronously. Default is 1
+	DispatchConcurrency int
+
+	// Order the events are handled, when dispatched asynchronously. Default is OrderPerSocket
+	DispatchOrdering DispatchOrdering
+
+	// Number of events queued before receiving packets is blocked, when dispatched asynchronously. Default is 64
+	DispatchQueueSize int
+
+	// Maximum duration to wait for the handlers being dispatched when disconnecting, after which the socket
+	// is disconnected without waiting for them, so a handler which doesn't watch its context doesn't block
+	// disconnecting. When zero, then it waits until the handlers return. Default is 5s
+	DispatchStopTimeout time.Duration
+
+	// Number of packets queued to be sent by the socket's writer, before emitting is blocked. When zero, then
+	// the packets are sent directly by the goroutine emitting. Default is 256
+	WriteQueueSize int
+
+	// Maximum duration for sending the queued packets when disconnecting, after which the connection is closed,
+	// so a client which stopped reading doesn't block disconnecting. When zero, then it waits until the packets
+	// are sent. Default is 5s
+	WriteFlushTimeout time.Duration
+
+	// Number of room messages buffered by the socket's client, before sending to the client waits for the messages
+	// to be received and a volatile room message is dropped. Default is 64
+	ClientBufferSize int
+
+	// Maximum number of queued packets coalesced into a single "batch" packet, when the client supports
+	// the CapabilityBatch capability and it's enabled in the Capabilities. Default is 32
+	BatchMaxPackets int
+
+	// Maximum duration to wait for more packets to coalesce into a batch. When zero, then only the packets
+	// already queued are coalesced. Default is 0
+	BatchFlushInterval time.Duration
+
+	// Minimum size in bytes of a packet before it's compressed, when the CapabilityCompression capability
+	// was negotiated and it's enabled in the Capabilities. Default is 1024
+	CompressionMinBytes int
+
+	// Whether all the events received and emitted are logged at the debug level, with only the number of args
+	// as they can contain credentials, which can be toggled at runtime as the value is shared between the
+	// sockets. Default is nil i.e. events are never logged
+	DebugEvents *atomic.Bool
+
+	// Limits of the packets received by the socket, where a violation disconnects the socket. Default is DefaultLimits()
+	Limits Limits
+
+	// Protocol versions supported by the server, where a client using another version is rejected. Default is ProtocolVersion
+	ProtocolVersions []int
+
+	// Capabilities supported by the server, which are enabled when the client supports them too. Default is CapabilityAckErrors
+	Capabilities []string
+
+	// Rate limit of all the events received by the socket. Default is nil i.e. unlimited
+	RateLimit *RateLimit
+
+	// Rate limits of the events received by the socket, where the key is the event name. Default is nil i.e. unlimited
+	EventRateLimits map[string]RateLimit
+
+	// Rate limiter of the events received across all the sockets of a remote IP. Default is nil i.e. unlimited
+	IPRateLimiter *IPRateLimiter
+
+	// Policy applied when an event is rate limited. Default is RateLimitDrop
+	RateLimitPolicy RateLimitPolicy
+
+	// Number of rate limited events before the socket is disconnected, when the policy is RateLimitDisconnect. Default is 10
+	RateLimitMaxViolations int
+
+	// Policy applied when a malformed packet is received. Default is MalformedPacketReport
+	MalformedPacketPolicy MalformedPacketPolicy
+
+	// Number of malformed packets before the socket is disconnected, when the policy is MalformedPacketDisconnect. Default is 10
+	MaxMalformedPackets int
+}
+
+// NewSocketConfig initializes a socket configuration instance with reasonable defaults.
+func NewSocketConfig() *Config {
+	cfg := &Config{
+		Logger: slog.Default(),
+		Tracer: nil,
+
+		ErrorHandler: nil,
+		Middlewares:  nil,
+		AuthTimeout:  0,
+
+		AsyncDispatch:       false,
+		DispatchConcurrency: 1,
+		DispatchOrdering:    OrderPerSocket,
+		DispatchQueueSize:   64,
+		DispatchStopTimeout: 5 * time.Second,
+
+		WriteQueueSize:     256,
+		WriteFlushTimeout:  5 * time.Second,
+		ClientBufferSize:   64,
+		BatchMaxPackets:    32,
+		BatchFlushInterval: 0,
+
+		CompressionMinBytes: 1024,
+
+		DebugEvents: nil,
+		Limits:      DefaultLimits(),
+
+		ProtocolVersions: []int{ProtocolVersion},
+		Capabilities:     []string{CapabilityAckErrors},
+
+		RateLimit:              nil,
+		EventRateLimits:        nil,
+		IPRateLimiter:          nil,
+		RateLimitPolicy:        RateLimitDrop,
+		RateLimitMaxViolations: 10,
+
+		MalformedPacketPolicy: MalformedPacketReport,
+		MaxMalformedPackets:   10,
+	}
+	return cfg
+}
@@ -0,0 +1,43 @@
+package socket
+
+// DataKey is the key of a value stored in a socket, where the type of the value is defined by the key
+type DataKey[T any] struct {
+	name string
+}
+
+// NewDataKey initializes a key, where the name is only used for debugging. Keys are compared by identity,
+// so keys with the same name are different keys
+func NewDataKey[T any](name string) *DataKey[T] {
+	return &DataKey[T]{
+		name: name,
+	}
+}
+
+func (k *DataKey[T]) String() string {
+	return k.name
+}
+
+// GetData returns the value of the key stored in the socket, otherwise false when there isn't one
+func GetData[T any](s *Socket, key *DataKey[T]) (T, bool) {
+	s.dataMu.RLock()
+	defer s.dataMu.RUnlock()
+
+	v, ok := s.data[key].(T)
+	return v, ok
+}
+
+// SetData stores the value of the key in the socket
+func SetData[T any](s *Socket, key *DataKey[T], v T) {
+	s.dataMu.Lock()
+	defer s.dataMu.Unlock()
+
+	s.data[key] = v
+}
+
+// DeleteData removes the value of the key from the socket
+func DeleteData[T any](s *Socket, key *DataKey[T]) {
+	s.dataMu.Lock()
+	defer s.dataMu.Unlock()
+
+	delete(s.data, key)
+}
@@ -0,0 +1,125 @@
+package socket
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// DispatchOrdering defines the order the events are handled, when the handlers are dispatched asynchronously
+type DispatchOrdering int
+
+const (
+	// Events are handled one at a time in the order received, regardless of the concurrency
+	OrderPerSocket DispatchOrdering = iota
+
+	// Events with the same name are handled in the order received, where different events are handled concurrently
+	OrderPerEvent
+
+	// Events are handled concurrently without any order
+	OrderNone
+)
+
+type dispatchJob struct {
+	ctx   context.Context
+	event string
+	ackID int
+	args  []any
+}
+
+// dispatcher handles the events on workers, so the packet loop isn't blocked by slow handlers
+// and keeps receiving e.g. acknowledgements
+type dispatcher struct {
+	ordering DispatchOrdering
+	queues   []chan dispatchJob
+
+	stopOnce sync.Once
+	stopCh   chan empty
+	wg       sync.WaitGroup
+}
+
+func newDispatcher(s *Socket) *dispatcher {
+	concurrency := max(1, s.cfg.DispatchConcurrency)
+	queueSize := max(0, s.cfg.DispatchQueueSize)
+
+	// A queue per worker when ordered by event, otherwise the workers share a single queue
+	queuesCount := 1
+	switch s.cfg.DispatchOrdering {
+	case OrderPerSocket:
+		concurrency = 1
+	case OrderPerEvent:
+		queuesCount = concurrency
+	}
+
+	d := &dispatcher{
+		ordering: s.cfg.DispatchOrdering,
+		queues:   make([]chan dispatchJob, queuesCount),
+		stopCh:   make(chan empty),
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan dispatchJob, queueSize)
+	}
+	for i := range concurrency {
+		queue := d.queues[i%queuesCount]
+
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+
+			for job := range queue {
+				select {
+				case <-d.stopCh:
+					// Skip the queued events of a disconnected socket
+				default:
+					s.dispatch(job.ctx, job.event, job.ackID, job.args)
+				}
+			}
+		}()
+	}
+	return d
+}
+
+// enqueue adds the event to the queue of the worker, which blocks when the queue is full
+func (d *dispatcher) enqueue(job dispatchJob) {
+	queue := d.queues[0]
+	if d.ordering == OrderPerEvent {
+		h := fnv.New32a()
+		h.Write([]byte(job.event))
+		queue = d.queues[h.Sum32()%uint32(len(d.queues))]
+	}
+	queue <- job
+}
+
+// stop skips the queued events and waits for the events being handled. When they aren't handled before
+// the timeout, then false is returned, where the handlers are still running. When the timeout is zero,
+// then it waits until they are handled. It must be called from the packet loop, as it's the only goroutine
+// which enqueues the events
+func (d *dispatcher) stop(timeout time.Duration) bool {
+	d.stopOnce.Do(func() {
+		close(d.stopCh)
+		for _, queue := range d.queues {
+			close(queue)
+		}
+	})
+
+	doneCh := make(chan empty)
+	go func() {
+		d.wg.Wait()
+		close(doneCh)
+	}()
+	if timeout <= 0 {
+		<-doneCh
+		return true
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-doneCh:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
@@ -0,0 +1,137 @@
+package socket
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_SocketAsyncDispatch(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.AsyncDispatch = true
+	cfg.DispatchOrdering = OrderPerSocket
+
+	s, a := newTestSocket(t, cfg)
+
+	unblockCh := make(chan struct{})
+	handledCh := make(chan string, 3)
+	s.On("slow", func(args ...any) {
+		<-unblockCh
+		handledCh <- "slow"
+	})
+	s.On("fast", func(args ...any) {
+		handledCh <- "fast"
+	})
+	connectTestSocket(t, s, a)
+
+	ackCh := make(chan struct{})
+	testhelpers.AssertNoError(t, s.Emit("question", func(...any) {
+		close(ackCh)
+	}))
+	testhelpers.AssertEqual(t, (<-a.sent).Type, "event")
+
+	a.receiveEvent("slow", 0)
+	a.receiveEvent("fast", 0)
+
+	// The acknowledgement is received while the "slow" handler is blocked
+	a.received <- Packet{Type: "ack", Data: map[string]any{"id": float64(1), "args": []any{}}}
+	<-ackCh
+	testhelpers.AssertEqual(t, len(handledCh), 0)
+
+	// The events are handled in order
+	close(unblockCh)
+	testhelpers.AssertEqual(t, <-handledCh, "slow")
+	testhelpers.AssertEqual(t, <-handledCh, "fast")
+
+	a.Close()
+}
+
+func Test_SocketAsyncDispatchConcurrent(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.AsyncDispatch = true
+	cfg.DispatchConcurrency = 2
+	cfg.DispatchOrdering = OrderNone
+
+	s, a := newTestSocket(t, cfg)
+
+	unblockCh := make(chan struct{})
+	handledCh := make(chan string, 2)
+	s.On("slow", func(args ...any) {
+		<-unblockCh
+		handledCh <- "slow"
+	})
+	s.On("fast", func(args ...any) {
+		handledCh <- "fast"
+	})
+
+	done := connectTestSocket(t, s, a)
+
+	a.receiveEvent("slow", 0)
+	a.receiveEvent("fast", 0)
+	testhelpers.AssertEqual(t, <-handledCh, "fast")
+
+	close(unblockCh)
+	testhelpers.AssertEqual(t, <-handledCh, "slow")
+
+	a.Close()
+	testhelpers.AssertNoError(t, <-done)
+}
+
+func Test_SocketAsyncDispatchDisconnect(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.AsyncDispatch = true
+
+	s, a := newTestSocket(t, cfg)
+
+	startedCh := make(chan struct{})
+	abortedCh := make(chan error, 1)
+	s.OnContext("stuck", func(ctx context.Context, _ ...any) {
+		close(startedCh)
+		<-ctx.Done()
+		abortedCh <- context.Cause(ctx)
+	})
+
+	done := connectTestSocket(t, s, a)
+
+	a.receiveEvent("stuck", 0)
+	<-startedCh
+
+	// The handler is aborted when disconnecting, so it doesn't block the disconnect
+	a.Close()
+	testhelpers.AssertNoError(t, <-done)
+	testhelpers.AssertEqual(t, errors.Is(<-abortedCh, ErrClientUnreachable), true)
+	testhelpers.AssertEqual(t, s.Context().Err() != nil, true)
+}
+
+func Test_SocketAsyncDispatchStopTimeout(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.AsyncDispatch = true
+	cfg.DispatchStopTimeout = 50 * time.Millisecond
+
+	s, a := newTestSocket(t, cfg)
+
+	startedCh := make(chan struct{})
+	releaseCh := make(chan struct{})
+	s.On("stuck", func(_ ...any) {
+		close(startedCh)
+		<-releaseCh
+	})
+
+	done := connectTestSocket(t, s, a)
+
+	a.receiveEvent("stuck", 0)
+	<-startedCh
+
+	// The handler doesn't watch its context, so it's only waited for until the timeout
+	a.Close()
+	select {
+	case err := <-done:
+		testhelpers.AssertNoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the socket to disconnect")
+	}
+	close(releaseCh)
+}
@@ -0,0 +1,68 @@
+package socket
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+var ErrAuthNotReceived = errors.New("socket: auth packet not received")
+
+// Handshake is the metadata of the HTTP request, which established the connection of the socket
+type Handshake struct {
+	Headers    http.Header
+	Query      url.Values
+	RemoteAddr string
+
+	// Time the connection was established
+	Time time.Time
+
+	// Auth is the bearer token of the "Authorization" header, otherwise the token of the "auth" packet
+	Auth string
+}
+
+func newHandshake(r *http.Request) Handshake {
+	h := Handshake{
+		Headers:    r.Header.Clone(),
+		Query:      r.URL.Query(),
+		RemoteAddr: r.RemoteAddr,
+		Time:       time.Now(),
+		Auth:       "",
+	}
+	if token, ok := strings.CutPrefix(h.Headers.Get("Authorization"), "Bearer "); ok {
+		h.Auth = token
+	}
+	return h
+}
+
+// receiveAuth receives the "auth" packet, which is the first packet the client sends with its credentials,
+// as browsers can't set the "Authorization" header of a WebSocket connection. The credentials aren't sent
+// in the query string, as the URL is likely logged
+func receiveAuth(conn *websocket.Conn, adapter Adapter, timeout time.Duration) (string, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return "", fmt.Errorf("socket: setting the auth packet deadline: %w", err)
+	}
+	pkt, err := adapter.Receive()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrAuthNotReceived, err)
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return "", fmt.Errorf("socket: clearing the auth packet deadline: %w", err)
+	}
+
+	token, ok := pkt.Data["token"].(string)
+	if pkt.Type != "auth" || !ok {
+		return "", ErrAuthNotReceived
+	}
+	return token, nil
+}
+
+// Middleware is called before the socket is connected and before any packet is received from the client,
+// where returning an error rejects the connection with the error as the reason e.g. when the credentials
+// of the handshake are invalid
+type Middleware func(s *Socket) error
@@ -0,0 +1,156 @@
+package socket
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+	"golang.org/x/net/websocket"
+)
+
+func Test_NewHandshake(t *testing.T) {
+	r := httptest.NewRequest("GET", "/chat?auth=query-token&room=root", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+
+	// The credentials aren't read from the query string
+	h := newHandshake(r)
+	testhelpers.AssertEqual(t, h.Auth, "")
+	testhelpers.AssertEqual(t, h.Query.Get("room"), "root")
+	testhelpers.AssertEqual(t, h.RemoteAddr, "10.0.0.1:1234")
+	testhelpers.AssertEqual(t, h.Time.IsZero(), false)
+
+	r.Header.Set("Authorization", "Bearer header-token")
+	h = newHandshake(r)
+	testhelpers.AssertEqual(t, h.Auth, "header-token")
+	testhelpers.AssertEqual(t, h.Headers.Get("Authorization"), "Bearer header-token")
+}
+
+func Test_SocketData(t *testing.T) {
+	s, err := New(newTestAdapter(), nil)
+	testhelpers.AssertNoError(t, err)
+
+	nameKey := NewDataKey[string]("name")
+	otherNameKey := NewDataKey[string]("name")
+	countKey := NewDataKey[int]("count")
+
+	_, ok := GetData(s, nameKey)
+	testhelpers.AssertEqual(t, ok, false)
+
+	SetData(s, nameKey, "chatterbox")
+	SetData(s, countKey, 1)
+
+	name, ok := GetData(s, nameKey)
+	testhelpers.AssertEqual(t, ok, true)
+	testhelpers.AssertEqual(t, name, "chatterbox")
+
+	count, ok := GetData(s, countKey)
+	testhelpers.AssertEqual(t, ok, true)
+	testhelpers.AssertEqual(t, count, 1)
+
+	// Keys are compared by identity
+	_, ok = GetData(s, otherNameKey)
+	testhelpers.AssertEqual(t, ok, false)
+
+	DeleteData(s, nameKey)
+	_, ok = GetData(s, nameKey)
+	testhelpers.AssertEqual(t, ok, false)
+}
+
+func Test_IOMiddleware(t *testing.T) {
+	errInvalidToken := errors.New("invalid token")
+
+	cfg := NewSocketConfig()
+	cfg.AuthTimeout = time.Second
+	cfg.Middlewares = []Middleware{
+		func(s *Socket) error {
+			if s.Handshake().Auth != "secret" {
+				return errInvalidToken
+			}
+			return nil
+		},
+	}
+
+	errCh := make(chan error, 1)
+	srv := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		errCh <- IO(conn, cfg, func(s *Socket) error {
+			s.On("ping", func(args ...any) {
+				if ackFn, ok := GetAckFunc(args); ok {
+					ackFn("pong")
+				}
+			})
+			return nil
+		})
+	}))
+	defer srv.Close()
+
+	dial := func(token string) *websocket.Conn {
+		conn, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/", "", srv.URL)
+		testhelpers.AssertNoError(t, err)
+
+		err = websocket.JSON.Send(conn, Packet{
+			Type: "auth",
+			Data: map[string]any{
+				"token": token,
+			},
+		})
+		testhelpers.AssertNoError(t, err)
+		return conn
+	}
+
+	// Rejected
+	conn := dial("invalid")
+	var pkt Packet
+	testhelpers.AssertNoError(t, websocket.JSON.Receive(conn, &pkt))
+	testhelpers.AssertEqual(t, pkt.Type, "connect_error")
+	testhelpers.AssertEqual(t, pkt.Data["reason"], any(errInvalidToken.Error()))
+	testhelpers.AssertEqual(t, errors.Is(<-errCh, errInvalidToken), true)
+	conn.Close()
+
+	// Rejected, as the first packet isn't the "auth" packet
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/", "", srv.URL)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertNoError(t, websocket.JSON.Send(conn, newTestEventPacket("ping", 1)))
+	testhelpers.AssertNoError(t, websocket.JSON.Receive(conn, &pkt))
+	testhelpers.AssertEqual(t, pkt.Type, "connect_error")
+	testhelpers.AssertEqual(t, errors.Is(<-errCh, ErrAuthNotReceived), true)
+	conn.Close()
+
+	// Accepted
+	conn = dial("secret")
+	testhelpers.AssertNoError(t, websocket.JSON.Receive(conn, &pkt))
+	testhelpers.AssertEqual(t, pkt.Type, "connect")
+
+	testhelpers.AssertNoError(t, websocket.JSON.Send(conn, newTestEventPacket("ping", 1)))
+	testhelpers.AssertNoError(t, websocket.JSON.Receive(conn, &pkt))
+	testhelpers.AssertEqual(t, pkt.Type, "ack")
+	testhelpers.AssertEqual(t, pkt.Data["args"], any([]any{"pong"}))
+
+	conn.Close()
+	testhelpers.AssertNoError(t, <-errCh)
+}
+
+func Test_SocketNotReceivingBeforeConnect(t *testing.T) {
+	a := newTestAdapter()
+	a.receiveEvent("ping", 0)
+
+	s, err := New(a, nil)
+	testhelpers.AssertNoError(t, err)
+
+	// The packets aren't received until connecting, so the middlewares run before any event is dispatched
+	time.Sleep(10 * time.Millisecond)
+	testhelpers.AssertEqual(t, len(a.received), 1)
+
+	pingCh := make(chan empty, 1)
+	s.On("ping", func(_ ...any) {
+		pingCh <- empty{}
+	})
+
+	done := connectTestSocket(t, s, a)
+	<-pingCh
+
+	go a.Close()
+	testhelpers.AssertNoError(t, <-done)
+}
@@ -1,16 +1,50 @@
 package socket
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 
 	"golang.org/x/net/websocket"
 )
 
-func IO(conn *websocket.Conn, initFn func(s *Socket) error) error {
-	s, err := New(NewWebSocketAdapter(conn))
+func IO(conn *websocket.Conn, cfg *Config, initFn func(s *Socket) error) error {
+	if cfg == nil {
+		cfg = NewSocketConfig()
+	}
+
+	// Copy the configuration, so the logger of this socket includes the remote address
+	connCfg := *cfg
+	connCfg.Logger = cfg.Logger.With(slog.String("remote_addr", conn.Request().RemoteAddr))
+
+	adapter := NewWebSocketAdapter(conn, connCfg.Limits)
+
+	protocol, err := negotiateProtocol(&connCfg, conn.Request().URL.Query())
+	if err != nil {
+		return rejectConnect(adapter, err)
+	}
+	if protocol.HasCapability(CapabilityCompression) {
+		adapter.enableCompression(connCfg.CompressionMinBytes)
+	}
+
+	handshake := newHandshake(conn.Request())
+	if handshake.Auth == "" && connCfg.AuthTimeout > 0 {
+		if handshake.Auth, err = receiveAuth(conn, adapter, connCfg.AuthTimeout); err != nil {
+			return rejectConnect(adapter, err)
+		}
+	}
+
+	s, err := newSocket(adapter, &connCfg, protocol, handshake)
 	if err != nil {
 		return fmt.Errorf("socket: initializing socket: %w", err)
 	}
+
+	for _, middleware := range connCfg.Middlewares {
+		if err := middleware(s); err != nil {
+			return rejectConnect(adapter, err)
+		}
+	}
+
 	if err := initFn(s); err != nil {
 		return fmt.Errorf("socket: initializing socket with the initialization function: %w", err)
 	}
@@ -19,3 +53,14 @@ func IO(conn *websocket.Conn, initFn func(s *Socket) error) error {
 	}
 	return nil
 }
+
+// rejectConnect sends the reason the connection was rejected to the client and closes the connection
+func rejectConnect(adapter Adapter, err error) error {
+	sendErr := adapter.Send(Packet{
+		Type: "connect_error",
+		Data: map[string]any{
+			"reason": err.Error(),
+		},
+	})
+	return errors.Join(fmt.Errorf("socket: rejecting connection: %w", err), sendErr, adapter.Close())
+}
@@ -0,0 +1,110 @@
+package socket
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrPacketTooLarge = errors.New("socket: packet exceeds the maximum frame size")
+	ErrPacketInvalid  = errors.New("socket: packet exceeds the payload limits")
+)
+
+// Limits defines the limits of the packets received by the adapter, which are validated before
+// the packet is handled by the socket. A limit of zero is unlimited
+type Limits struct {
+	// Maximum size of a frame in bytes
+	MaxFrameBytes int
+
+	// Maximum number of args of an event or ack packet
+	MaxArgs int
+
+	// Maximum nesting depth of the arrays and objects of an arg
+	MaxDepth int
+
+	// Maximum length in bytes of the strings and object keys of the packet's data
+	MaxStringLength int
+}
+
+// DefaultLimits returns the default limits of the packets
+func DefaultLimits() Limits {
+	return Limits{
+		MaxFrameBytes:   64 << 10, // 64KB
+		MaxArgs:         16,
+		MaxDepth:        8,
+		MaxStringLength: 16 << 10, // 16KB
+	}
+}
+
+// validate returns an error wrapping ErrPacketInvalid, when the packet exceeds one of the limits
+func (l Limits) validate(pkt Packet) error {
+	if l.MaxArgs > 0 {
+		if args, ok := pkt.Data["args"].([]any); ok && len(args) > l.MaxArgs {
+			return fmt.Errorf("%w: %d args exceeds the maximum of %d", ErrPacketInvalid, len(args), l.MaxArgs)
+		}
+	}
+	if err := l.validateString(pkt.Type); err != nil {
+		return err
+	}
+
+	// The depth is relative to each arg, as the data and args are part of the packet's structure
+	for k, v := range pkt.Data {
+		if err := l.validateString(k); err != nil {
+			return err
+		}
+
+		values := []any{v}
+		if args, ok := v.([]any); ok && k == "args" {
+			values = args
+		}
+		for _, v := range values {
+			if err := l.validateValue(v, 0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l Limits) validateValue(v any, depth int) error {
+	switch v := v.(type) {
+	case string:
+		return l.validateString(v)
+	case []any:
+		if err := l.validateDepth(depth + 1); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := l.validateValue(item, depth+1); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		if err := l.validateDepth(depth + 1); err != nil {
+			return err
+		}
+		for k, item := range v {
+			if err := l.validateString(k); err != nil {
+				return err
+			}
+			if err := l.validateValue(item, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l Limits) validateDepth(depth int) error {
+	if l.MaxDepth > 0 && depth > l.MaxDepth {
+		return fmt.Errorf("%w: nesting depth exceeds the maximum of %d", ErrPacketInvalid, l.MaxDepth)
+	}
+	return nil
+}
+
+func (l Limits) validateString(s string) error {
+	if l.MaxStringLength > 0 && len(s) > l.MaxStringLength {
+		return fmt.Errorf("%w: string length of %d exceeds the maximum of %d", ErrPacketInvalid, len(s), l.MaxStringLength)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package socket
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+	"golang.org/x/net/websocket"
+)
+
+func Test_LimitsValidate(t *testing.T) {
+	limits := Limits{
+		MaxFrameBytes:   0,
+		MaxArgs:         2,
+		MaxDepth:        3,
+		MaxStringLength: 5,
+	}
+	newEventPacket := func(args ...any) Packet {
+		return newTestEventPacket("hello", 0, args...)
+	}
+
+	tests := []struct {
+		name string
+		pkt  Packet
+		ok   bool
+	}{
+		{"valid", newEventPacket("a", map[string]any{"b": []any{"c"}}), true},
+		{"too many args", newEventPacket("a", "b", "c"), false},
+		{"too deep", newEventPacket([]any{[]any{[]any{[]any{"a"}}}}), false},
+		{"string too long", newEventPacket("abcdef"), false},
+		{"key too long", newEventPacket(map[string]any{"abcdef": "a"}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := limits.validate(tt.pkt)
+			testhelpers.AssertEqual(t, err == nil, tt.ok)
+			if err != nil {
+				testhelpers.AssertEqual(t, errors.Is(err, ErrPacketInvalid), true)
+			}
+		})
+	}
+
+	// A zero limit is unlimited
+	testhelpers.AssertNoError(t, Limits{}.validate(newEventPacket("a", "b", "c", strings.Repeat("d", 100))))
+}
+
+func Test_WebSocketAdapterMaxFrameBytes(t *testing.T) {
+	errCh := make(chan error, 1)
+	srv := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		a := NewWebSocketAdapter(conn, Limits{MaxFrameBytes: 64})
+		_, err := a.Receive()
+		errCh <- err
+	}))
+	defer srv.Close()
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), "", srv.URL)
+	testhelpers.AssertNoError(t, err)
+	defer conn.Close()
+
+	testhelpers.AssertNoError(t, websocket.JSON.Send(conn, newTestEventPacket("message", 0, strings.Repeat("a", 128))))
+	testhelpers.AssertEqual(t, <-errCh, ErrPacketTooLarge)
+}
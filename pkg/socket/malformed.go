@@ -0,0 +1,68 @@
+package socket
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+var ErrMalformedPacket = errors.New("socket: malformed packet")
+
+// MalformedPacketPolicy defines what happens to a socket when a malformed packet is received
+type MalformedPacketPolicy int
+
+const (
+	// Log and ignore the packet
+	MalformedPacketIgnore MalformedPacketPolicy = iota
+
+	// Log and ignore the packet, and send an "error" packet with the reason to the client
+	MalformedPacketReport
+
+	// Report the packet and disconnect the socket after the maximum number of malformed packets
+	MalformedPacketDisconnect
+)
+
+// Bounded reasons of a malformed packet, which are used as the metric label
+const (
+	malformedUnknownType = "unknown_type"
+	malformedAckID       = "invalid_ack_id"
+	malformedAckArgs     = "invalid_ack_args"
+	malformedEventName   = "invalid_event_name"
+	malformedEventArgs   = "invalid_event_args"
+	malformedEventAckID  = "invalid_event_ack_id"
+)
+
+// onMalformedPacket applies the malformed packet policy and returns true when the socket was disconnected
+func (s *Socket) onMalformedPacket(pkt Packet, reason string, value any) bool {
+	malformedCounter.WithLabelValues(reason).Inc()
+	s.malformedPackets++
+
+	s.logger.Warn(
+		"received a malformed packet",
+		slog.String("type", pkt.Type),
+		slog.String("reason", reason),
+		slog.Any("value", value),
+		slog.Int("malformed_packets", s.malformedPackets),
+	)
+
+	if s.cfg.MalformedPacketPolicy == MalformedPacketIgnore {
+		return false
+	}
+
+	err := fmt.Errorf("%w: %s", ErrMalformedPacket, reason)
+	sendErr := s.send(Packet{
+		Type: "error",
+		Data: map[string]any{
+			"reason": err.Error(),
+		},
+	})
+	if sendErr != nil {
+		s.logger.Warn("unable to send the error packet", slog.Any("error", sendErr))
+	}
+
+	if s.cfg.MalformedPacketPolicy == MalformedPacketDisconnect && s.malformedPackets >= max(1, s.cfg.MaxMalformedPackets) {
+		s.onDisconnect(err)
+		return true
+	}
+	return false
+}
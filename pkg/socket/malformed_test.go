@@ -0,0 +1,62 @@
+package socket
+
+import (
+	"testing"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_SocketMalformedPacketDisconnect(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.MalformedPacketPolicy = MalformedPacketDisconnect
+	cfg.MaxMalformedPackets = 2
+
+	s, a := newTestSocket(t, cfg)
+
+	done := connectTestSocket(t, s, a)
+
+	a.received <- Packet{Type: "unknown", Data: map[string]any{}}
+	pkt := <-a.sent
+	testhelpers.AssertEqual(t, pkt.Type, "error")
+	testhelpers.AssertEqual(t, pkt.Data["reason"], any("socket: malformed packet: unknown_type"))
+
+	// Missing the "ackId"
+	a.received <- Packet{Type: "event", Data: map[string]any{"event": "message", "args": []any{}}}
+	pkt = <-a.sent
+	testhelpers.AssertEqual(t, pkt.Type, "error")
+	testhelpers.AssertEqual(t, pkt.Data["reason"], any("socket: malformed packet: invalid_event_ack_id"))
+
+	pkt = <-a.sent
+	testhelpers.AssertEqual(t, pkt.Type, "disconnect")
+	testhelpers.AssertNoError(t, <-done)
+}
+
+func Test_SocketOnUnknown(t *testing.T) {
+	s, a := newTestSocket(t, nil)
+
+	type unknownEvent struct {
+		event string
+		args  Args
+	}
+	unknownCh := make(chan unknownEvent, 1)
+	s.On("known", func(args ...any) {
+		t.Error("expected the unknown handler to not be called for a known event")
+	})
+	s.OnUnknown(func(event string, args Args) {
+		if ackFn, ok := GetAckFunc(args); ok {
+			ackFn("unknown")
+			args = argDeleteLast(args)
+		}
+		unknownCh <- unknownEvent{event, args}
+	})
+	connectTestSocket(t, s, a)
+
+	a.receiveEvent("missing", 1, "hello")
+
+	pkt := <-a.sent
+	testhelpers.AssertEqual(t, pkt.Type, "ack")
+	testhelpers.AssertEqual(t, pkt.Data["args"], any([]any{"unknown"}))
+	testhelpers.AssertEqual(t, <-unknownCh, unknownEvent{"missing", Args{"hello"}})
+
+	a.Close()
+}
@@ -0,0 +1,56 @@
+package socket
+
+import (
+	"errors"
+
+	"github.com/softwarespot/chatterbox/pkg/metrics"
+)
+
+// Label used for the events without any subscribers, so clients can't create an unbounded number of label values
+const unknownEventLabel = "unknown"
+
+var (
+	activeSocketsGauge     = metrics.NewGauge("socket_active", "Number of connected sockets.")
+	connectsCounter        = metrics.NewCounter("socket_connects_total", "Total number of socket connections.")
+	disconnectsCounter     = metrics.NewCounterVec("socket_disconnects_total", "Total number of socket disconnections by reason.", "reason")
+	eventsCounter          = metrics.NewCounterVec("socket_events_received_total", "Total number of events received by event name.", "event")
+	ackLatencyHist         = metrics.NewHistogram("socket_ack_latency_seconds", "Latency between emitting an event and receiving its acknowledgement.", nil)
+	rateLimitedCounter     = metrics.NewCounterVec("socket_rate_limited_total", "Total number of events rate limited by scope and event name.", "scope", "event")
+	malformedCounter       = metrics.NewCounterVec("socket_malformed_packets_total", "Total number of malformed packets received by reason.", "reason")
+	volatileDroppedCounter = metrics.NewCounter("socket_volatile_dropped_total", "Total number of volatile events dropped, as the socket wasn't ready to send them.")
+	panicsCounter          = metrics.NewCounterVec("socket_handler_panics_total", "Total number of handlers which panicked by event name.", "event")
+	batchSizeHist          = metrics.NewHistogram("socket_batch_packets", "Number of packets coalesced into a batch packet.", []float64{2, 4, 8, 16, 32, 64})
+)
+
+func init() {
+	metrics.Default.MustRegister(
+		activeSocketsGauge,
+		connectsCounter,
+		disconnectsCounter,
+		eventsCounter,
+		ackLatencyHist,
+		rateLimitedCounter,
+		malformedCounter,
+		volatileDroppedCounter,
+		panicsCounter,
+		batchSizeHist,
+	)
+}
+
+// disconnectReason returns the bounded reason used as the metric label for the disconnection error
+func disconnectReason(err error) string {
+	switch {
+	case errors.Is(err, ErrClientUnreachable):
+		return "client_unreachable"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrPacketTooLarge):
+		return "packet_too_large"
+	case errors.Is(err, ErrPacketInvalid):
+		return "packet_invalid"
+	case errors.Is(err, ErrMalformedPacket):
+		return "malformed_packet"
+	default:
+		return "transport_error"
+	}
+}
@@ -0,0 +1,56 @@
+package socket
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/softwarespot/chatterbox/pkg/metrics"
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_SocketMetrics(t *testing.T) {
+	// Other tests' sockets may still be disconnecting, so only the label unique to this test is compared exactly
+	connects := connectsCounter.Value()
+	disconnects := disconnectsCounter.WithLabelValues("client_unreachable").Value()
+	events := eventsCounter.WithLabelValues("metrics").Value()
+	acks := ackLatencyHist.Count()
+
+	s, a := newTestSocket(t, nil)
+	handledCh := make(chan empty, 1)
+	s.On("metrics", func(_ ...any) {
+		handledCh <- empty{}
+	})
+	done := connectTestSocket(t, s, a)
+	testhelpers.AssertEqual(t, connectsCounter.Value() >= connects+1, true)
+
+	a.receiveEvent("metrics", 0)
+	<-handledCh
+	testhelpers.AssertEqual(t, eventsCounter.WithLabelValues("metrics").Value(), events+1)
+
+	ackCh := make(chan empty)
+	testhelpers.AssertNoError(t, s.Emit("question", func(...any) {
+		close(ackCh)
+	}))
+	pkt := <-a.sent
+	a.received <- Packet{Type: "ack", Data: map[string]any{"id": float64(pkt.Data["ackId"].(int)), "args": []any{}}}
+	<-ackCh
+	testhelpers.AssertEqual(t, ackLatencyHist.Count() >= acks+1, true)
+
+	a.Close()
+	testhelpers.AssertNoError(t, <-done)
+	testhelpers.AssertEqual(t, disconnectsCounter.WithLabelValues("client_unreachable").Value() >= disconnects+1, true)
+
+	var buf bytes.Buffer
+	_, err := metrics.Default.WriteTo(&buf)
+	testhelpers.AssertNoError(t, err)
+	for _, series := range []string{
+		"socket_active ",
+		"socket_connects_total ",
+		`socket_disconnects_total{reason="client_unreachable"} `,
+		`socket_events_received_total{event="metrics"} `,
+		"socket_ack_latency_seconds_count ",
+	} {
+		testhelpers.AssertEqual(t, strings.Contains(buf.String(), "\n"+series), true)
+	}
+}
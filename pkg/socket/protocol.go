@@ -0,0 +1,72 @@
+package socket
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ProtocolVersion is the current version of the wire protocol
+const ProtocolVersion = 1
+
+// Capabilities of the wire protocol, which are enabled when supported by both the client and server
+const (
+	// Acknowledgements can carry a structured error
+	CapabilityAckErrors = "ack-errors"
+)
+
+var ErrProtocolVersion = errors.New("socket: unsupported protocol version")
+
+// Query parameters of the connection URL, where the client sends its protocol version and capabilities
+// e.g. "/chat?protocol=1&capabilities=batch,ack-errors". When missing, then the client is assumed to
+// use version 1 with no capabilities
+const (
+	protocolQueryParam     = "protocol"
+	capabilitiesQueryParam = "capabilities"
+)
+
+// Protocol is the negotiated protocol of a socket
+type Protocol struct {
+	Version      int
+	Capabilities []string
+}
+
+// HasCapability returns true when the capability was negotiated
+func (p Protocol) HasCapability(capability string) bool {
+	return slices.Contains(p.Capabilities, capability)
+}
+
+// defaultProtocol returns the protocol of the clients, which don't send their protocol version
+func defaultProtocol() Protocol {
+	return Protocol{
+		Version:      1,
+		Capabilities: nil,
+	}
+}
+
+// negotiateProtocol returns the protocol for the version and capabilities of the client's query parameters.
+// The capabilities are the ones supported by both the client and server
+func negotiateProtocol(cfg *Config, query url.Values) (Protocol, error) {
+	p := defaultProtocol()
+	if v := query.Get(protocolQueryParam); v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return Protocol{}, fmt.Errorf("%w: %q", ErrProtocolVersion, v)
+		}
+		p.Version = version
+	}
+	if !slices.Contains(cfg.ProtocolVersions, p.Version) {
+		return Protocol{}, fmt.Errorf("%w: %d, where the supported versions are %v", ErrProtocolVersion, p.Version, cfg.ProtocolVersions)
+	}
+
+	for _, capability := range strings.Split(query.Get(capabilitiesQueryParam), ",") {
+		capability = strings.TrimSpace(capability)
+		if slices.Contains(cfg.Capabilities, capability) && !slices.Contains(p.Capabilities, capability) {
+			p.Capabilities = append(p.Capabilities, capability)
+		}
+	}
+	return p, nil
+}
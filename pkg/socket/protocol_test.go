@@ -0,0 +1,65 @@
+package socket
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_NegotiateProtocol(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.ProtocolVersions = []int{1, 2}
+	cfg.Capabilities = []string{CapabilityAckErrors, CapabilityBatch}
+
+	tests := []struct {
+		name  string
+		query string
+		want  Protocol
+		err   bool
+	}{
+		{"legacy client", "", Protocol{Version: 1, Capabilities: nil}, false},
+		{"supported version", "protocol=2", Protocol{Version: 2, Capabilities: nil}, false},
+		{"negotiated capabilities", "protocol=1&capabilities=compression,ack-errors,ack-errors", Protocol{Version: 1, Capabilities: []string{CapabilityAckErrors}}, false},
+		{"unsupported version", "protocol=3", Protocol{}, true},
+		{"invalid version", "protocol=v1", Protocol{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			testhelpers.AssertNoError(t, err)
+
+			got, err := negotiateProtocol(cfg, query)
+			testhelpers.AssertEqual(t, errors.Is(err, ErrProtocolVersion), tt.err)
+			testhelpers.AssertEqual(t, got, tt.want)
+		})
+	}
+}
+
+func Test_SocketConnectProtocol(t *testing.T) {
+	a := newTestAdapter()
+	s, err := newSocket(a, nil, Protocol{Version: 1, Capabilities: []string{CapabilityAckErrors}}, Handshake{})
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, s.Protocol().HasCapability(CapabilityAckErrors), true)
+	testhelpers.AssertEqual(t, s.Protocol().HasCapability(CapabilityBatch), false)
+
+	go s.onConnect()
+
+	pkt := <-a.sent
+	testhelpers.AssertEqual(t, pkt.Type, "connect")
+	testhelpers.AssertEqual(t, pkt.Data["version"], any(1))
+	testhelpers.AssertEqual(t, pkt.Data["capabilities"], any([]string{CapabilityAckErrors}))
+
+	a.Close()
+}
+
+func Test_RejectConnect(t *testing.T) {
+	a := newTestAdapter()
+	err := rejectConnect(a, ErrProtocolVersion)
+	testhelpers.AssertEqual(t, errors.Is(err, ErrProtocolVersion), true)
+
+	pkt := <-a.sent
+	testhelpers.AssertEqual(t, pkt.Type, "connect_error")
+	testhelpers.AssertEqual(t, pkt.Data["reason"], any(ErrProtocolVersion.Error()))
+}
@@ -0,0 +1,179 @@
+package socket
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRateLimited      = errors.New("socket: rate limit exceeded")
+	ErrInvalidRateLimit = errors.New("socket: rate limit must have a burst greater than zero and a rate not less than zero")
+)
+
+// RateLimit defines a token bucket, where tokens are refilled at the rate per second up to the burst.
+// Each received event takes a token and is limited when the bucket is empty
+type RateLimit struct {
+	// Number of events allowed per second
+	Rate float64
+
+	// Maximum number of events allowed at once
+	Burst int
+}
+
+func (l RateLimit) validate() error {
+	if l.Rate < 0 || l.Burst <= 0 {
+		return ErrInvalidRateLimit
+	}
+	return nil
+}
+
+// validateRateLimits returns an error wrapping ErrInvalidRateLimit, when one of the rate limits would limit every event
+func (cfg *Config) validateRateLimits() error {
+	if cfg.RateLimit != nil {
+		if err := cfg.RateLimit.validate(); err != nil {
+			return fmt.Errorf("socket: invalid socket rate limit: %w", err)
+		}
+	}
+	for event, limit := range cfg.EventRateLimits {
+		if err := limit.validate(); err != nil {
+			return fmt.Errorf("socket: invalid rate limit of event %q: %w", event, err)
+		}
+	}
+	if cfg.IPRateLimiter != nil {
+		if err := cfg.IPRateLimiter.limit.validate(); err != nil {
+			return fmt.Errorf("socket: invalid IP rate limit: %w", err)
+		}
+	}
+	return nil
+}
+
+// RateLimitPolicy defines what happens to a socket when an event is rate limited
+type RateLimitPolicy int
+
+const (
+	// Drop the event without calling the handlers
+	RateLimitDrop RateLimitPolicy = iota
+
+	// Drop the event and acknowledge it with the rate limited error, when the event has an "ack" function
+	RateLimitErrorAck
+
+	// Drop the event and disconnect the socket after the maximum number of violations
+	RateLimitDisconnect
+)
+
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+func newTokenBucket(limit RateLimit, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		limit:  limit,
+		tokens: float64(limit.Burst),
+		last:   now,
+	}
+}
+
+// allow takes a token from the bucket and returns true, otherwise false when the bucket is empty
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// available returns true when the bucket has a token, without taking it
+func (b *tokenBucket) available(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	return b.tokens >= 1
+}
+
+// full returns true when the bucket has been refilled to the burst i.e. it's been idle
+func (b *tokenBucket) full(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	return b.tokens >= float64(b.limit.Burst)
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(float64(b.limit.Burst), b.tokens+elapsed.Seconds()*b.limit.Rate)
+		b.last = now
+	}
+}
+
+// Interval between removing the buckets of the IPs, which have been idle
+const ipRateLimiterSweepInterval = time.Minute
+
+// IPRateLimiter limits the events received across all the sockets of a remote IP,
+// therefore it should be shared between the socket configurations
+type IPRateLimiter struct {
+	limit     RateLimit
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+func NewIPRateLimiter(limit RateLimit) *IPRateLimiter {
+	return &IPRateLimiter{
+		limit:     limit,
+		buckets:   map[string]*tokenBucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket of the IP and returns true, otherwise false when the bucket is empty
+func (l *IPRateLimiter) Allow(ip string) bool {
+	return l.allow(ip, time.Now())
+}
+
+func (l *IPRateLimiter) allow(ip string, now time.Time) bool {
+	l.mu.Lock()
+	if now.Sub(l.lastSweep) >= ipRateLimiterSweepInterval {
+		for k, b := range l.buckets {
+			if b.full(now) {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = newTokenBucket(l.limit, now)
+		l.buckets[ip] = b
+	}
+	l.mu.Unlock()
+
+	return b.allow(now)
+}
+
+// remoteIP returns the IP of the adapter's remote address, which is empty when the adapter doesn't expose it
+func remoteIP(adapter Adapter) string {
+	ra, ok := adapter.(interface{ RemoteAddr() string })
+	if !ok {
+		return ""
+	}
+
+	addr := ra.RemoteAddr()
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
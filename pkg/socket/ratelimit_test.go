@@ -0,0 +1,145 @@
+package socket
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_TokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(RateLimit{Rate: 2, Burst: 3}, now)
+
+	for range 3 {
+		testhelpers.AssertEqual(t, b.allow(now), true)
+	}
+	testhelpers.AssertEqual(t, b.allow(now), false)
+	testhelpers.AssertEqual(t, b.full(now), false)
+
+	// Refills 2 tokens per second
+	now = now.Add(500 * time.Millisecond)
+	testhelpers.AssertEqual(t, b.allow(now), true)
+	testhelpers.AssertEqual(t, b.allow(now), false)
+
+	// Never refills more than the burst
+	now = now.Add(time.Hour)
+	testhelpers.AssertEqual(t, b.full(now), true)
+	for range 3 {
+		testhelpers.AssertEqual(t, b.allow(now), true)
+	}
+	testhelpers.AssertEqual(t, b.allow(now), false)
+}
+
+func Test_IPRateLimiter(t *testing.T) {
+	now := time.Now()
+	l := NewIPRateLimiter(RateLimit{Rate: 1, Burst: 1})
+
+	testhelpers.AssertEqual(t, l.allow("10.0.0.1", now), true)
+	testhelpers.AssertEqual(t, l.allow("10.0.0.1", now), false)
+	testhelpers.AssertEqual(t, l.allow("10.0.0.2", now), true)
+
+	// The idle buckets are removed
+	now = now.Add(2 * ipRateLimiterSweepInterval)
+	testhelpers.AssertEqual(t, l.allow("10.0.0.1", now), true)
+	testhelpers.AssertEqual(t, len(l.buckets), 1)
+}
+
+func Test_SocketRateLimitErrorAck(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.EventRateLimits = map[string]RateLimit{
+		"message": {Rate: 0, Burst: 1},
+	}
+	cfg.RateLimitPolicy = RateLimitErrorAck
+
+	s, a := newTestSocket(t, cfg)
+
+	received := make(chan any, 2)
+	s.On("message", func(args ...any) {
+		received <- args[0]
+	})
+	connectTestSocket(t, s, a)
+
+	a.receiveEvent("message", 0, "first")
+	a.receiveEvent("message", 1, "second")
+
+	testhelpers.AssertEqual(t, <-received, any("first"))
+	pkt := <-a.sent
+	testhelpers.AssertEqual(t, pkt.Type, "ack")
+	testhelpers.AssertEqual(t, pkt.Data["args"], any([]any{ErrRateLimited.Error()}))
+	testhelpers.AssertEqual(t, len(received), 0)
+}
+
+func Test_SocketRateLimitDisconnect(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.RateLimit = &RateLimit{Rate: 0, Burst: 1}
+	cfg.RateLimitPolicy = RateLimitDisconnect
+	cfg.RateLimitMaxViolations = 2
+
+	s, a := newTestSocket(t, cfg)
+
+	done := connectTestSocket(t, s, a)
+
+	for range 3 {
+		a.receiveEvent("message", 0, "hello")
+	}
+
+	pkt := <-a.sent
+	testhelpers.AssertEqual(t, pkt.Type, "disconnect")
+	testhelpers.AssertEqual(t, pkt.Data["reason"], any(ErrRateLimited.Error()))
+	testhelpers.AssertNoError(t, <-done)
+	testhelpers.AssertEqual(t, s.Disconnected(), true)
+}
+
+// remoteAddrAdapter is a test adapter, which exposes the remote address used by the IP rate limiter
+type remoteAddrAdapter struct {
+	*testAdapter
+}
+
+func (a remoteAddrAdapter) RemoteAddr() string {
+	return "10.0.0.1:1234"
+}
+
+func Test_SocketRateLimitIP(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.RateLimit = &RateLimit{Rate: 0, Burst: 1}
+	cfg.IPRateLimiter = NewIPRateLimiter(RateLimit{Rate: 0, Burst: 2})
+	cfg.RateLimitPolicy = RateLimitErrorAck
+
+	a := newTestAdapter()
+	s, err := New(remoteAddrAdapter{a}, cfg)
+	testhelpers.AssertNoError(t, err)
+
+	s.On("message", func(_ ...any) {})
+	connectTestSocket(t, s, a)
+
+	a.receiveEvent("message", 1, "first")
+	a.receiveEvent("message", 2, "second")
+	a.receiveEvent("message", 3, "third")
+
+	for _, id := range []int{2, 3} {
+		pkt := <-a.sent
+		testhelpers.AssertEqual(t, pkt.Type, "ack")
+		testhelpers.AssertEqual(t, pkt.Data["id"], any(id))
+	}
+
+	// The events limited by the socket didn't take a token from the IP bucket
+	testhelpers.AssertEqual(t, cfg.IPRateLimiter.Allow("10.0.0.1"), true)
+	testhelpers.AssertEqual(t, cfg.IPRateLimiter.Allow("10.0.0.1"), false)
+}
+
+func Test_SocketRateLimitInvalid(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.RateLimit = &RateLimit{Rate: 1, Burst: 0}
+
+	_, err := New(newTestAdapter(), cfg)
+	testhelpers.AssertEqual(t, errors.Is(err, ErrInvalidRateLimit), true)
+
+	cfg = NewSocketConfig()
+	cfg.EventRateLimits = map[string]RateLimit{
+		"message": {Rate: -1, Burst: 1},
+	}
+	_, err = New(newTestAdapter(), cfg)
+	testhelpers.AssertEqual(t, errors.Is(err, ErrInvalidRateLimit), true)
+}
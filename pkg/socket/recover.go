@@ -0,0 +1,68 @@
+package socket
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+)
+
+// ErrHandlerPanicked is sent to the client as the acknowledgement of an event, when its handler panicked
+var ErrHandlerPanicked = errors.New("socket: handler panicked")
+
+// HandlerPanicError is the error of a handler, which panicked
+type HandlerPanicError struct {
+	Event string
+	Value any
+	Stack []byte
+}
+
+func (e *HandlerPanicError) Error() string {
+	return fmt.Sprintf("socket: handler of the event %q panicked: %v", e.Event, e.Value)
+}
+
+func (e *HandlerPanicError) Unwrap() error {
+	return ErrHandlerPanicked
+}
+
+// callHandler calls the handler of the event and returns true when it panicked. The panic is recovered
+// and reported to the error handler, so the socket is kept alive
+func (s *Socket) callHandler(event string, fn func()) (panicked bool) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		panicked = true
+
+		err := &HandlerPanicError{
+			Event: event,
+			Value: v,
+			Stack: debug.Stack(),
+		}
+		panicsCounter.WithLabelValues(s.eventLabel(event)).Inc()
+
+		if s.cfg.ErrorHandler != nil {
+			s.cfg.ErrorHandler(s, err)
+			return
+		}
+		s.logger.Error("recovered from a handler panic", slog.String("event", event), slog.Any("error", err), slog.String("stack", string(err.Stack)))
+	}()
+
+	fn()
+	return false
+}
+
+// AckError returns the args of an acknowledgement with the error e.g. ackFn(s.AckError(err)...).
+// When the client supports the "ack-errors" capability, then the error is structured as {"message": "..."},
+// otherwise it's the error message
+func (s *Socket) AckError(err error) []any {
+	if s.protocol.HasCapability(CapabilityAckErrors) {
+		return []any{
+			map[string]any{
+				"message": err.Error(),
+			},
+		}
+	}
+	return []any{err.Error()}
+}
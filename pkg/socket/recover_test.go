@@ -0,0 +1,53 @@
+package socket
+
+import (
+	"errors"
+	"testing"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_SocketHandlerPanic(t *testing.T) {
+	errCh := make(chan error, 1)
+
+	cfg := NewSocketConfig()
+	cfg.ErrorHandler = func(s *Socket, err error) {
+		errCh <- err
+	}
+
+	a := newTestAdapter()
+	s, err := newSocket(a, cfg, Protocol{Version: 1, Capabilities: []string{CapabilityAckErrors}}, Handshake{})
+	testhelpers.AssertNoError(t, err)
+
+	handledCh := make(chan string, 1)
+	s.On("panic", func(args ...any) {
+		panic("boom")
+	})
+	s.On("message", func(args ...any) {
+		msg, _ := ArgAt[string](args, 0)
+		handledCh <- msg
+	})
+	connectTestSocket(t, s, a)
+
+	a.receiveEvent("panic", 1)
+
+	err = <-errCh
+	testhelpers.AssertEqual(t, errors.Is(err, ErrHandlerPanicked), true)
+
+	var panicErr *HandlerPanicError
+	testhelpers.AssertEqual(t, errors.As(err, &panicErr), true)
+	testhelpers.AssertEqual(t, panicErr.Event, "panic")
+	testhelpers.AssertEqual(t, panicErr.Value, any("boom"))
+	testhelpers.AssertEqual(t, len(panicErr.Stack) > 0, true)
+
+	pkt := <-a.sent
+	testhelpers.AssertEqual(t, pkt.Type, "ack")
+	testhelpers.AssertEqual(t, pkt.Data["args"], any([]any{map[string]any{"message": ErrHandlerPanicked.Error()}}))
+
+	// The socket is kept alive
+	a.receiveEvent("message", 0, "hello")
+	testhelpers.AssertEqual(t, <-handledCh, "hello")
+	testhelpers.AssertEqual(t, s.Connected(), true)
+
+	a.Close()
+}
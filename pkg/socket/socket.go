@@ -1,63 +1,247 @@
 package socket
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/softwarespot/chatterbox/pkg/room"
+	"github.com/softwarespot/chatterbox/pkg/trace"
 )
 
+var ErrAckNotReceived = errors.New("socket: acknowledgement not received before disconnecting")
+
 type empty struct{}
 
 type Packet struct {
 	Type string         `json:"type"`
 	Data map[string]any `json:"data"`
+
+	// Trace is optional and is the span context of the sender, so traces can be followed across the client and server
+	Trace *trace.SpanContext `json:"trace,omitempty"`
+}
+
+type pendingAck struct {
+	event  string
+	fn     func(...any)
+	sentAt time.Time
+	span   *trace.Span
 }
 
 type Socket struct {
-	subscribers map[string][]func(args ...any)
+	cfg    *Config
+	logger *slog.Logger
+
+	subscribers        map[string][]func(args ...any)
+	ctxSubscribers     map[string][]func(ctx context.Context, args ...any)
+	unknownSubscribers []func(event string, args Args)
+
+	incomingListeners anyListeners
+	outgoingListeners anyListeners
+	anyMu             sync.RWMutex
 
-	adapter Adapter
-	client  *room.Client[Args]
+	adapter   Adapter
+	writeMu   sync.Mutex
+	client    atomic.Pointer[room.Client[Args]]
+	protocol  Protocol
+	handshake Handshake
 
-	connected bool
+	data   map[any]any
+	dataMu sync.RWMutex
+
+	connected atomic.Bool
+
+	// Cancelled with the reason when disconnecting, so the handlers of the events being dispatched are aborted
+	ctx    context.Context
+	cancel context.CancelCauseFunc
 
 	ackID  int
-	ackFns map[int]func(...any)
+	ackFns map[int]pendingAck
+	ackMu  sync.Mutex
+
+	// Only accessed by the packet loop
+	remoteIP       string
+	rateLimiter    *tokenBucket
+	eventLimiters  map[string]*tokenBucket
+	rateViolations int
+
+	malformedPackets int
+
+	// Only set when the handlers are dispatched asynchronously
+	dispatcher *dispatcher
+
+	// Only set when the packets are queued
+	writer *writer
 
 	disconnectedCh chan empty
 }
 
-func New(adapter Adapter) (*Socket, error) {
+func New(adapter Adapter, cfg *Config) (*Socket, error) {
+	return newSocket(adapter, cfg, defaultProtocol(), Handshake{Time: time.Now()})
+}
+
+func newSocket(adapter Adapter, cfg *Config, protocol Protocol, handshake Handshake) (*Socket, error) {
+	if cfg == nil {
+		cfg = NewSocketConfig()
+	}
 	s := &Socket{
-		subscribers: map[string][]func(args ...any){},
+		cfg:    cfg,
+		logger: nil,
+
+		subscribers:    map[string][]func(args ...any){},
+		ctxSubscribers: map[string][]func(ctx context.Context, args ...any){},
 
-		adapter: adapter,
-		client:  nil,
+		unknownSubscribers: nil,
 
-		connected: false,
+		incomingListeners: anyListeners{},
+		outgoingListeners: anyListeners{},
+
+		adapter:   adapter,
+		protocol:  protocol,
+		handshake: handshake,
+
+		data: map[any]any{},
 
 		ackID:  0,
-		ackFns: map[int]func(...any){},
+		ackFns: map[int]pendingAck{},
+
+		remoteIP:       remoteIP(adapter),
+		rateLimiter:    nil,
+		eventLimiters:  map[string]*tokenBucket{},
+		rateViolations: 0,
+
+		malformedPackets: 0,
 
 		disconnectedCh: make(chan empty),
 	}
 
-	var err error
-	if s.client, err = room.NewClient[Args](); err != nil {
+	if err := cfg.validateRateLimits(); err != nil {
 		return nil, err
 	}
 
-	go s.onPacket()
+	client, err := room.NewBufferedClient[Args](cfg.ClientBufferSize)
+	if err != nil {
+		return nil, err
+	}
+	s.client.Store(client)
+	s.logger = cfg.Logger.With(slog.String("socket_id", client.ID()))
+	s.ctx, s.cancel = context.WithCancelCause(context.Background())
+	if cfg.RateLimit != nil {
+		s.rateLimiter = newTokenBucket(*cfg.RateLimit, time.Now())
+	}
+	if cfg.DebugEvents != nil {
+		s.logEvents()
+	}
 
 	return s, nil
 }
 
+// start starts receiving the packets, which is once the middlewares have accepted the connection,
+// so no event is dispatched for a rejected connection
+func (s *Socket) start() {
+	if s.cfg.AsyncDispatch {
+		s.dispatcher = newDispatcher(s)
+	}
+	if s.cfg.WriteQueueSize > 0 {
+		s.writer = newWriter(s)
+	}
+
+	go s.onPacket()
+}
+
 func (s *Socket) emit(event string, args ...any) {
+	s.emitContext(context.Background(), event, args...)
+}
+
+// emitContext calls the handlers of the event and returns true when one of them panicked
+func (s *Socket) emitContext(ctx context.Context, event string, args ...any) bool {
+	var panicked bool
 	for _, fn := range s.subscribers[event] {
-		fn(args...)
+		if s.callHandler(event, func() { fn(args...) }) {
+			panicked = true
+		}
+	}
+	for _, fn := range s.ctxSubscribers[event] {
+		if s.callHandler(event, func() { fn(ctx, args...) }) {
+			panicked = true
+		}
+	}
+	return panicked
+}
+
+func (s *Socket) hasSubscribers(event string) bool {
+	return len(s.subscribers[event]) > 0 || len(s.ctxSubscribers[event]) > 0
+}
+
+// eventLabel returns the bounded event name used as the metric label
+func (s *Socket) eventLabel(event string) string {
+	if s.hasSubscribers(event) {
+		return event
+	}
+	return unknownEventLabel
+}
+
+// rateLimited returns true with the scope of the rate limit, when the event exceeds one of the rate limits
+func (s *Socket) rateLimited(event string) (string, bool) {
+	now := time.Now()
+
+	var eventLimiter *tokenBucket
+	if limit, ok := s.cfg.EventRateLimits[event]; ok {
+		eventLimiter = s.eventLimiters[event]
+		if eventLimiter == nil {
+			eventLimiter = newTokenBucket(limit, now)
+			s.eventLimiters[event] = eventLimiter
+		}
+	}
+
+	// The socket and event buckets are checked before taking a token from the IP bucket, which is shared with
+	// the other sockets of the IP, so an event limited by the socket doesn't use up the budget of the IP
+	if s.rateLimiter != nil && !s.rateLimiter.available(now) {
+		return "socket", true
+	}
+	if eventLimiter != nil && !eventLimiter.available(now) {
+		return "event", true
+	}
+	if s.cfg.IPRateLimiter != nil && s.remoteIP != "" && !s.cfg.IPRateLimiter.allow(s.remoteIP, now) {
+		return "ip", true
+	}
+
+	// The buckets are only accessed by the packet loop, so the tokens checked above are still available
+	if s.rateLimiter != nil {
+		s.rateLimiter.allow(now)
+	}
+	if eventLimiter != nil {
+		eventLimiter.allow(now)
+	}
+	return "", false
+}
+
+// onRateLimited applies the rate limit policy to the event and returns true when the socket was disconnected
+func (s *Socket) onRateLimited(event, scope string, ackID int) bool {
+	rateLimitedCounter.WithLabelValues(scope, s.eventLabel(event)).Inc()
+	s.rateViolations++
+
+	s.logger.Warn("rate limited the event", slog.String("event", event), slog.String("scope", scope), slog.Int("violations", s.rateViolations))
+
+	switch s.cfg.RateLimitPolicy {
+	case RateLimitErrorAck:
+		if ackID > 0 {
+			if err := s.emitAck(context.Background(), ackID, s.AckError(ErrRateLimited)...); err != nil {
+				s.logger.Warn("unable to acknowledge the rate limited event", slog.String("event", event), slog.Any("error", err))
+			}
+		}
+	case RateLimitDisconnect:
+		if s.rateViolations >= max(1, s.cfg.RateLimitMaxViolations) {
+			s.onDisconnect(ErrRateLimited)
+			return true
+		}
 	}
+	return false
 }
 
 func (s *Socket) on(event string, fn func(args ...any)) {
@@ -67,16 +251,18 @@ func (s *Socket) on(event string, fn func(args ...any)) {
 func (s *Socket) off(event string, fn func(args ...any)) {
 	if event == "" && fn == nil {
 		clear(s.subscribers)
+		clear(s.ctxSubscribers)
 		return
 	}
 
-	fns, ok := s.subscribers[event]
-	if !ok {
+	if fn == nil {
+		delete(s.subscribers, event)
+		delete(s.ctxSubscribers, event)
 		return
 	}
 
-	if fn == nil {
-		delete(s.subscribers, event)
+	fns, ok := s.subscribers[event]
+	if !ok {
 		return
 	}
 
@@ -99,62 +285,152 @@ func (s *Socket) onPacket() {
 		case "ack":
 			id, ok := pkt.Data["id"].(float64)
 			if !ok {
-				log.Printf("invalid id type: %v", pkt.Data["id"])
+				if s.onMalformedPacket(pkt, malformedAckID, pkt.Data["id"]) {
+					return
+				}
 				continue
 			}
 			ackID := int(id)
 
 			args, ok := pkt.Data["args"].([]any)
 			if !ok {
-				log.Printf("invalid args type: %v", pkt.Data["args"])
+				if s.onMalformedPacket(pkt, malformedAckArgs, pkt.Data["args"]) {
+					return
+				}
 				continue
 			}
-			if ackFn, ok := s.ackFns[ackID]; ok {
-				ackFn(args...)
-				delete(s.ackFns, ackID)
+			args = s.decodeArgs(args)
+
+			s.ackMu.Lock()
+			pa, ok := s.ackFns[ackID]
+			delete(s.ackFns, ackID)
+			s.ackMu.Unlock()
+
+			if ok {
+				ackLatencyHist.Observe(time.Since(pa.sentAt).Seconds())
+				pa.span.End()
+				s.callHandler(pa.event, func() { pa.fn(args...) })
 			}
 		case "event":
 			event, ok := pkt.Data["event"].(string)
 			if !ok {
-				log.Printf("invalid event type: %v", pkt.Data["event"])
+				if s.onMalformedPacket(pkt, malformedEventName, pkt.Data["event"]) {
+					return
+				}
 				continue
 			}
 
 			args, ok := pkt.Data["args"].([]any)
 			if !ok {
-				log.Printf("invalid args type: %v", pkt.Data["args"])
+				if s.onMalformedPacket(pkt, malformedEventArgs, pkt.Data["args"]) {
+					return
+				}
 				continue
 			}
+			args = s.decodeArgs(args)
 
 			id, ok := pkt.Data["ackId"].(float64)
 			if !ok {
-				log.Printf("invalid ackId type: %v", pkt.Data["ackId"])
+				if s.onMalformedPacket(pkt, malformedEventAckID, pkt.Data["ackId"]) {
+					return
+				}
 				continue
 			}
 			ackID := int(id)
 
-			if ackID > 0 {
-				args = append(args, func(args ...any) {
-					s.emitAck(ackID, args...)
+			eventsCounter.WithLabelValues(s.eventLabel(event)).Inc()
+
+			if scope, limited := s.rateLimited(event); limited {
+				if disconnected := s.onRateLimited(event, scope, ackID); disconnected {
+					return
+				}
+				continue
+			}
+
+			ctx := s.ctx
+			if pkt.Trace != nil {
+				ctx = trace.ContextWithRemoteSpanContext(ctx, *pkt.Trace)
+			}
+			if s.dispatcher != nil {
+				s.dispatcher.enqueue(dispatchJob{
+					ctx:   ctx,
+					event: event,
+					ackID: ackID,
+					args:  args,
 				})
+			} else {
+				s.dispatch(ctx, event, ackID, args)
+			}
+		case "auth":
+			// The credentials are only received before connecting
+		default:
+			if s.onMalformedPacket(pkt, malformedUnknownType, pkt.Type) {
+				return
+			}
+		}
+	}
+}
+
+// dispatch calls the handlers of the event received. When a handler panics and the event expects
+// an acknowledgement, which wasn't sent, then the acknowledgement is sent with an error
+func (s *Socket) dispatch(ctx context.Context, event string, ackID int, args []any) {
+	ctx, span := s.cfg.Tracer.Start(ctx, "socket.event")
+	defer span.End()
+	span.SetAttribute("socket_id", s.ID())
+	span.SetAttribute("event", event)
+
+	s.callHandler(event, func() { s.emitAny(&s.incomingListeners, event, args) })
+
+	// Only the first acknowledgement is sent
+	var acked atomic.Bool
+	if ackID > 0 {
+		args = append(args, func(args ...any) {
+			if acked.CompareAndSwap(false, true) {
+				s.emitAck(ctx, ackID, args...)
+			}
+		})
+	}
+
+	var panicked bool
+	if s.hasSubscribers(event) {
+		panicked = s.emitContext(ctx, event, args...)
+	} else {
+		for _, fn := range s.unknownSubscribers {
+			if s.callHandler(event, func() { fn(event, args) }) {
+				panicked = true
 			}
-			s.emit(event, args...)
+		}
+	}
+	if !panicked {
+		return
+	}
+
+	span.SetError(ErrHandlerPanicked)
+	if ackID > 0 && acked.CompareAndSwap(false, true) {
+		if err := s.emitAck(ctx, ackID, s.AckError(ErrHandlerPanicked)...); err != nil {
+			s.logger.Warn("unable to acknowledge the event with the error", slog.String("event", event), slog.Any("error", err))
 		}
 	}
 }
 
 func (s *Socket) onConnect() error {
-	err := s.adapter.Send(Packet{
+	s.start()
+
+	err := s.send(Packet{
 		Type: "connect",
 		Data: map[string]any{
-			"id": s.ID(),
+			"id":           s.ID(),
+			"version":      s.protocol.Version,
+			"capabilities": ensureNonEmptyCapabilities(s.protocol.Capabilities),
 		},
 	})
 	if err != nil {
 		return fmt.Errorf("socket: sending connect packet: %w", err)
 	}
 
-	s.connected = true
+	s.connected.Store(true)
+	connectsCounter.Inc()
+	activeSocketsGauge.Inc()
 
 	s.emit("connect", s.ID())
 
@@ -165,86 +441,253 @@ func (s *Socket) onConnect() error {
 func (s *Socket) onDisconnect(err error) error {
 	defer close(s.disconnectedCh)
 
-	if !s.connected {
+	// Abort the handlers being dispatched e.g. waiting on a room, then wait for them, so they don't run
+	// concurrently with the "disconnect" handlers. A handler which doesn't watch its context is only
+	// waited for until the timeout
+	s.cancel(err)
+	if s.dispatcher != nil && !s.dispatcher.stop(s.cfg.DispatchStopTimeout) {
+		s.logger.Warn("timed out waiting for the handlers", slog.Duration("timeout", s.cfg.DispatchStopTimeout))
+	}
+
+	if !s.connected.Load() {
+		s.stopWriter()
 		return nil
 	}
 
+	activeSocketsGauge.Dec()
+	disconnectsCounter.WithLabelValues(disconnectReason(err)).Inc()
+
+	// The socket's context is cancelled, so the "disconnect" packet is queued until the flush timeout instead.
+	// The client is likely unreachable when sending fails, so continue disconnecting
+	ctx := context.Background()
+	if s.cfg.WriteFlushTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.WriteFlushTimeout)
+		defer cancel()
+	}
+
 	reason := err.Error()
-	err = s.adapter.Send(Packet{
+	err = s.sendContext(ctx, Packet{
 		Type: "disconnect",
 		Data: map[string]any{
 			"reason": reason,
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("socket: sending disconnect packet: %w", err)
+		s.logger.Debug("unable to send the disconnect packet", slog.Any("error", err))
 	}
 
-	s.connected = false
-	s.client = nil
+	s.connected.Store(false)
+	s.client.Store(nil)
 
+	s.ackMu.Lock()
+	for _, pa := range s.ackFns {
+		pa.span.SetError(ErrAckNotReceived)
+		pa.span.End()
+	}
 	s.ackID = 0
 	clear(s.ackFns)
+	s.ackMu.Unlock()
 
 	s.emit("disconnect", reason)
 
-	if err := s.adapter.Close(); err != nil {
+	closed, err := s.stopWriter()
+	if !closed {
+		err = s.adapter.Close()
+	}
+	if err != nil {
 		return fmt.Errorf("socket: closing websocket connection: %w", err)
 	}
 	return nil
 }
 
+// stopWriter sends the queued packets. When they aren't sent before the flush timeout e.g. the client
+// stopped reading, then the connection is closed to abort sending, and true is returned
+func (s *Socket) stopWriter() (bool, error) {
+	if s.writer == nil || s.writer.stop(s.cfg.WriteFlushTimeout) {
+		return false, nil
+	}
+
+	s.logger.Warn("timed out sending the queued packets", slog.Duration("timeout", s.cfg.WriteFlushTimeout))
+	err := s.adapter.Close()
+	s.writer.wait()
+	return true, err
+}
+
 func (s *Socket) ID() string {
-	if s.client == nil {
+	client := s.client.Load()
+	if client == nil {
 		return ""
 	}
-	return s.client.ID()
+	return client.ID()
+}
+
+// Context returns the context of the socket, which is cancelled with the reason when disconnecting.
+// It's the parent of the context passed to the handlers registered with OnContext
+func (s *Socket) Context() context.Context {
+	return s.ctx
+}
+
+// Logger returns the logger of the socket, which includes the "socket_id" attribute
+func (s *Socket) Logger() *slog.Logger {
+	return s.logger
 }
 
 func (s *Socket) Client() *room.Client[Args] {
-	return s.client
+	return s.client.Load()
+}
+
+// Handshake returns the metadata of the request, which established the connection
+func (s *Socket) Handshake() Handshake {
+	return s.handshake
+}
+
+// Protocol returns the protocol negotiated with the client
+func (s *Socket) Protocol() Protocol {
+	return s.protocol
 }
 
 func (s *Socket) Connected() bool {
-	return s.connected
+	return s.connected.Load()
 }
 
 func (s *Socket) Disconnected() bool {
-	return !s.connected
+	return !s.connected.Load()
 }
 
+// Emit emits the event. When the packets are queued, then the error of a previous packet which failed
+// to be sent is returned, as the event is sent by the socket's writer
 func (s *Socket) Emit(event string, args ...any) error {
+	return s.EmitContext(context.Background(), event, args...)
+}
+
+// EmitContext emits the event, where the span context of the context is propagated to the client.
+// When the last arg is an "ack" function, then the span ends when the acknowledgement is received
+func (s *Socket) EmitContext(ctx context.Context, event string, args ...any) error {
+	return s.emitPacket(ctx, event, args, false)
+}
+
+func (s *Socket) emitPacket(ctx context.Context, event string, args []any, volatile bool) error {
+	// A disconnected socket isn't ready to send a volatile event
+	if volatile && !s.connected.Load() {
+		volatileDroppedCounter.Inc()
+		return nil
+	}
+
+	ctx, span := s.cfg.Tracer.Start(ctx, "socket.emit")
+	span.SetAttribute("socket_id", s.ID())
+	span.SetAttribute("event", event)
+	span.SetAttribute("volatile", volatile)
+
 	var ackID int
 	if ackFn, ok := GetAckFunc(args); ok {
+		s.ackMu.Lock()
 		s.ackID++
-		s.ackFns[s.ackID] = ackFn
+		s.ackFns[s.ackID] = pendingAck{
+			event:  event,
+			fn:     ackFn,
+			sentAt: time.Now(),
+			span:   span,
+		}
+		ackID = s.ackID
+		s.ackMu.Unlock()
 
 		// Remove the "ack" function
 		args = argDeleteLast(args)
-		ackID = s.ackID
+	} else {
+		defer span.End()
 	}
+	s.emitAny(&s.outgoingListeners, event, args)
 
-	err := s.adapter.Send(Packet{
+	pkt := Packet{
 		Type: "event",
 		Data: map[string]any{
 			"event": event,
-			"args":  ensureNonEmptyArgs(args),
+			"args":  ensureNonEmptyArgs(s.encodeArgs(args)),
 			"ackId": ackID,
 		},
-	})
+		Trace: packetTrace(ctx),
+	}
+	if volatile {
+		sent, err := s.trySend(pkt)
+		if !sent && err == nil {
+			volatileDroppedCounter.Inc()
+			span.SetAttribute("dropped", true)
+			s.removeAck(ackID)
+			return nil
+		}
+		if err != nil {
+			span.SetError(err)
+			return fmt.Errorf("socket: calling emit: %w", err)
+		}
+		return nil
+	}
+
+	err := s.send(pkt)
 	if err != nil {
+		span.SetError(err)
 		return fmt.Errorf("socket: calling emit: %w", err)
 	}
 	return nil
 }
 
-func (s *Socket) emitAck(id int, args ...any) error {
-	err := s.adapter.Send(Packet{
+// send sends the packet, waiting for any packet being sent. When the packets are queued, then it waits
+// for the packet to be queued only, until the socket is disconnected
+func (s *Socket) send(pkt Packet) error {
+	return s.sendContext(s.ctx, pkt)
+}
+
+// sendContext sends the packet like send, where it waits for the packet to be queued until the context is done
+func (s *Socket) sendContext(ctx context.Context, pkt Packet) error {
+	if s.writer != nil {
+		return s.writer.enqueue(ctx, pkt)
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return s.adapter.Send(pkt)
+}
+
+// trySend sends the packet only when no other packet is being sent or the queue isn't full,
+// and returns false when it wasn't sent
+func (s *Socket) trySend(pkt Packet) (bool, error) {
+	if s.writer != nil {
+		return s.writer.tryEnqueue(pkt)
+	}
+
+	if !s.writeMu.TryLock() {
+		return false, nil
+	}
+	defer s.writeMu.Unlock()
+
+	return true, s.adapter.Send(pkt)
+}
+
+// removeAck removes the "ack" function of the event, which was never sent
+func (s *Socket) removeAck(ackID int) {
+	if ackID == 0 {
+		return
+	}
+
+	s.ackMu.Lock()
+	pa, ok := s.ackFns[ackID]
+	delete(s.ackFns, ackID)
+	s.ackMu.Unlock()
+
+	if ok {
+		pa.span.End()
+	}
+}
+
+func (s *Socket) emitAck(ctx context.Context, id int, args ...any) error {
+	err := s.send(Packet{
 		Type: "ack",
 		Data: map[string]any{
 			"id":   id,
-			"args": ensureNonEmptyArgs(args),
+			"args": ensureNonEmptyArgs(s.encodeArgs(args)),
 		},
+		Trace: packetTrace(ctx),
 	})
 	if err != nil {
 		return fmt.Errorf("socket: calling emitAck: %w", err)
@@ -257,11 +700,42 @@ func (s *Socket) On(event string, fn func(args ...any)) *Socket {
 	return s
 }
 
+// OnContext registers the function for the event, where the context contains the span context of the event.
+// The context is cancelled when disconnecting, where a dispatched handler must return, as it's only waited
+// for until the configuration's DispatchStopTimeout
+func (s *Socket) OnContext(event string, fn func(ctx context.Context, args ...any)) *Socket {
+	s.ctxSubscribers[event] = append(s.ctxSubscribers[event], fn)
+	return s
+}
+
+// OnUnknown registers the function for the events without any subscribers, where the last arg is
+// the "ack" function when the client expects an acknowledgement
+func (s *Socket) OnUnknown(fn func(event string, args Args)) *Socket {
+	s.unknownSubscribers = append(s.unknownSubscribers, fn)
+	return s
+}
+
 func (s *Socket) Off(event string, fn func(args ...any)) *Socket {
 	s.off(event, fn)
 	return s
 }
 
+// packetTrace returns the span context of the context to add to a packet, which is nil when there isn't one
+func packetTrace(ctx context.Context) *trace.SpanContext {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return &sc
+}
+
+func ensureNonEmptyCapabilities(capabilities []string) []string {
+	if len(capabilities) == 0 {
+		return []string{}
+	}
+	return capabilities
+}
+
 func ensureNonEmptyArgs(args []any) []any {
 	if len(args) == 0 {
 		return []any{}
@@ -0,0 +1,158 @@
+package socket
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+	"github.com/softwarespot/chatterbox/pkg/trace"
+)
+
+// testAdapter is an in-memory adapter, where the packets received by the socket are written to
+// the "received" channel and the packets sent by the socket are read from the "sent" channel
+type testAdapter struct {
+	received chan Packet
+	sent     chan Packet
+
+	closeOnce sync.Once
+	closedCh  chan struct{}
+}
+
+func newTestAdapter() *testAdapter {
+	return &testAdapter{
+		received: make(chan Packet, 16),
+		sent:     make(chan Packet, 16),
+		closedCh: make(chan struct{}),
+	}
+}
+
+func (a *testAdapter) receiveEvent(event string, ackID int, args ...any) {
+	a.received <- newTestEventPacket(event, ackID, args...)
+}
+
+func newTestEventPacket(event string, ackID int, args ...any) Packet {
+	return Packet{
+		Type: "event",
+		Data: map[string]any{
+			"event": event,
+			"args":  ensureNonEmptyArgs(args),
+			"ackId": float64(ackID),
+		},
+	}
+}
+
+func (a *testAdapter) Receive() (Packet, error) {
+	select {
+	case pkt := <-a.received:
+		return pkt, nil
+	case <-a.closedCh:
+		return Packet{}, ErrClientUnreachable
+	}
+}
+
+func (a *testAdapter) Send(pkt Packet) error {
+	select {
+	case a.sent <- pkt:
+		return nil
+	case <-a.closedCh:
+		return ErrClientUnreachable
+	}
+}
+
+func (a *testAdapter) Close() error {
+	a.closeOnce.Do(func() {
+		close(a.closedCh)
+	})
+	return nil
+}
+
+// newTestSocket initializes a socket with a test adapter. When the configuration is nil, then the default
+// configuration is used
+func newTestSocket(t *testing.T, cfg *Config) (*Socket, *testAdapter) {
+	t.Helper()
+
+	a := newTestAdapter()
+	s, err := New(a, cfg)
+	testhelpers.AssertNoError(t, err)
+	return s, a
+}
+
+// connectTestSocket connects the socket and waits for the "connect" packet, where the returned channel
+// receives the error of the connection once the socket is disconnected
+func connectTestSocket(t *testing.T, s *Socket, a *testAdapter) <-chan error {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.onConnect()
+	}()
+	testhelpers.AssertEqual(t, (<-a.sent).Type, "connect")
+	return done
+}
+
+// waitSpans waits for the number of spans to be exported
+func waitSpans(exp *trace.InMemoryExporter, count int) []trace.SpanData {
+	for {
+		if spans := exp.Spans(); len(spans) >= count {
+			return spans
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func Test_SocketTracer(t *testing.T) {
+	exp := trace.NewInMemoryExporter()
+	tracer := trace.NewTracer(exp)
+
+	cfg := NewSocketConfig()
+	cfg.Tracer = tracer
+
+	s, a := newTestSocket(t, cfg)
+	s.On("question", func(args ...any) {
+		if ackFn, ok := GetAckFunc(args); ok {
+			ackFn("answer")
+		}
+	})
+	done := connectTestSocket(t, s, a)
+
+	// The span context of the received packet is the parent of the event's span, which is sent with the ack
+	remote := trace.SpanContext{
+		TraceID: "0af7651916cd43dd8448eb211c80319c",
+		SpanID:  "b7ad6b7169203331",
+	}
+	pkt := newTestEventPacket("question", 1)
+	pkt.Trace = &remote
+	a.received <- pkt
+
+	ack := <-a.sent
+	testhelpers.AssertEqual(t, ack.Type, "ack")
+
+	spans := waitSpans(exp, 1)
+	testhelpers.AssertEqual(t, spans[0].Name, "socket.event")
+	testhelpers.AssertEqual(t, spans[0].TraceID, remote.TraceID)
+	testhelpers.AssertEqual(t, spans[0].ParentSpanID, remote.SpanID)
+	testhelpers.AssertEqual(t, spans[0].Attributes["event"], any("question"))
+	testhelpers.AssertEqual(t, ack.Trace != nil, true)
+	testhelpers.AssertEqual(t, *ack.Trace, spans[0].SpanContext)
+
+	// The emitted packet is sent with the span context of the emit's span
+	exp.Reset()
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	testhelpers.AssertNoError(t, s.EmitContext(ctx, "message", "hello"))
+	parent.End()
+
+	pkt = <-a.sent
+	testhelpers.AssertEqual(t, pkt.Type, "event")
+
+	spans = waitSpans(exp, 2)
+	testhelpers.AssertEqual(t, spans[0].Name, "socket.emit")
+	testhelpers.AssertEqual(t, spans[0].TraceID, parent.SpanContext().TraceID)
+	testhelpers.AssertEqual(t, spans[0].ParentSpanID, parent.SpanContext().SpanID)
+	testhelpers.AssertEqual(t, pkt.Trace != nil, true)
+	testhelpers.AssertEqual(t, *pkt.Trace, spans[0].SpanContext)
+
+	a.Close()
+	testhelpers.AssertNoError(t, <-done)
+}
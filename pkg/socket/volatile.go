@@ -0,0 +1,29 @@
+package socket
+
+import (
+	"context"
+)
+
+// VolatileEmitter emits events, which are dropped when the socket isn't ready to send them e.g. the socket
+// is still sending a previous packet or is disconnected. Useful for events where delivery doesn't matter
+// e.g. typing indicators or cursor positions
+type VolatileEmitter struct {
+	s *Socket
+}
+
+// Volatile returns an emitter of volatile events
+func (s *Socket) Volatile() *VolatileEmitter {
+	return &VolatileEmitter{
+		s: s,
+	}
+}
+
+// Emit emits the event, which is dropped without an error when the socket isn't ready to send it.
+// When the last arg is an "ack" function and the event is dropped, then the function is never called
+func (v *VolatileEmitter) Emit(event string, args ...any) error {
+	return v.EmitContext(context.Background(), event, args...)
+}
+
+func (v *VolatileEmitter) EmitContext(ctx context.Context, event string, args ...any) error {
+	return v.s.emitPacket(ctx, event, args, true)
+}
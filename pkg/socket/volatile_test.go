@@ -0,0 +1,65 @@
+package socket
+
+import (
+	"testing"
+	"time"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_SocketVolatileEmit(t *testing.T) {
+	// Send the packets directly
+	cfg := NewSocketConfig()
+	cfg.WriteQueueSize = 0
+
+	s, a := newTestSocket(t, cfg)
+
+	// Dropped when disconnected
+	testhelpers.AssertNoError(t, s.Volatile().Emit("typing", "a"))
+	testhelpers.AssertEqual(t, len(a.sent), 0)
+
+	connectTestSocket(t, s, a)
+
+	testhelpers.AssertNoError(t, s.Volatile().Emit("typing", "b"))
+	pkt := <-a.sent
+	testhelpers.AssertEqual(t, pkt.Data["args"], any([]any{"b"}))
+
+	// Dropped when another packet is being sent, including the "ack" function
+	s.writeMu.Lock()
+	testhelpers.AssertNoError(t, s.Volatile().Emit("typing", "c", func(...any) {}))
+	s.writeMu.Unlock()
+	testhelpers.AssertEqual(t, len(a.sent), 0)
+
+	s.ackMu.Lock()
+	testhelpers.AssertEqual(t, len(s.ackFns), 0)
+	s.ackMu.Unlock()
+
+	a.Close()
+}
+
+func Test_SocketVolatileEmitQueueFull(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.WriteQueueSize = 1
+
+	// The writer is blocked until a packet is read
+	s, a := newTestSocket(t, cfg)
+	a.sent = make(chan Packet)
+	connectTestSocket(t, s, a)
+
+	// Wait for the writer to be blocked sending the first event, then fill the queue with the second event
+	testhelpers.AssertNoError(t, s.Emit("message", "1"))
+	for len(s.writer.queue) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	testhelpers.AssertNoError(t, s.Emit("message", "2"))
+
+	testhelpers.AssertNoError(t, s.Volatile().Emit("typing", "dropped"))
+
+	testhelpers.AssertEqual(t, (<-a.sent).Data["args"], any([]any{"1"}))
+	testhelpers.AssertEqual(t, (<-a.sent).Data["args"], any([]any{"2"}))
+
+	testhelpers.AssertNoError(t, s.Volatile().Emit("typing", "sent"))
+	testhelpers.AssertEqual(t, (<-a.sent).Data["args"], any([]any{"sent"}))
+
+	a.Close()
+}
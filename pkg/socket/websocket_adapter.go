@@ -1,6 +1,7 @@
 package socket
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -8,34 +9,120 @@ import (
 	"golang.org/x/net/websocket"
 )
 
+var ErrClientUnreachable = errors.New("socket: client unreachable when receiving")
+
+// frame is a received WebSocket frame, where a binary frame is compressed when compression was negotiated
+type frame struct {
+	data   []byte
+	binary bool
+}
+
+// frameCodec receives a frame with its payload type
+var frameCodec = websocket.Codec{
+	Marshal: nil,
+	Unmarshal: func(data []byte, payloadType byte, v any) error {
+		f := v.(*frame)
+		f.data = data
+		f.binary = payloadType == websocket.BinaryFrame
+		return nil
+	},
+}
+
 type WebSocketAdapter struct {
-	conn *websocket.Conn
+	conn   *websocket.Conn
+	limits Limits
+
+	// Only set when compression was negotiated, which is before sending or receiving any packet
+	compress         bool
+	compressMinBytes int
 }
 
-func NewWebSocketAdapter(conn *websocket.Conn) *WebSocketAdapter {
+// NewWebSocketAdapter initializes an adapter for the connection, where the received packets are validated
+// against the limits
+func NewWebSocketAdapter(conn *websocket.Conn, limits Limits) *WebSocketAdapter {
+	if limits.MaxFrameBytes > 0 {
+		conn.MaxPayloadBytes = limits.MaxFrameBytes
+	}
 	return &WebSocketAdapter{
-		conn: conn,
+		conn:   conn,
+		limits: limits,
+
+		compress:         false,
+		compressMinBytes: 0,
 	}
 }
 
+// enableCompression sends the packets of at least the size as deflate compressed binary frames, and
+// decompresses the binary frames received
+func (w *WebSocketAdapter) enableCompression(minBytes int) {
+	w.compress = true
+	w.compressMinBytes = minBytes
+}
+
 func (w *WebSocketAdapter) Receive() (Packet, error) {
-	var pkt Packet
-	if err := websocket.JSON.Receive(w.conn, &pkt); err != nil {
+	var f frame
+	if err := frameCodec.Receive(w.conn, &f); err != nil {
 		if errors.Is(err, io.EOF) {
-			return Packet{}, errors.New("socket: client unreachable when receiving")
+			return Packet{}, ErrClientUnreachable
+		}
+		if errors.Is(err, websocket.ErrFrameTooLarge) {
+			return Packet{}, ErrPacketTooLarge
 		}
 		return Packet{}, fmt.Errorf("socket: client unreachable when receiving with error: %w", err)
 	}
+
+	data := f.data
+	if f.binary && w.compress {
+		var err error
+		if data, err = inflate(data, w.maxPacketBytes()); err != nil {
+			return Packet{}, err
+		}
+	}
+
+	var pkt Packet
+	if err := json.Unmarshal(data, &pkt); err != nil {
+		return Packet{}, fmt.Errorf("socket: client unreachable when receiving with error: %w", err)
+	}
+	if err := w.limits.validate(pkt); err != nil {
+		return Packet{}, err
+	}
 	return pkt, nil
 }
 
+// maxPacketBytes returns the maximum size of a decompressed packet, which is the maximum frame size
+func (w *WebSocketAdapter) maxPacketBytes() int {
+	if w.limits.MaxFrameBytes > 0 {
+		return w.limits.MaxFrameBytes
+	}
+	return websocket.DefaultMaxPayloadBytes
+}
+
 func (w *WebSocketAdapter) Send(pkt Packet) error {
-	if err := websocket.JSON.Send(w.conn, pkt); err != nil {
+	data, err := json.Marshal(pkt)
+	if err != nil {
+		return fmt.Errorf("socket: encoding the packet: %w", err)
+	}
+
+	// A binary frame is sent for a []byte, otherwise a text frame for a string
+	if w.compress && len(data) >= w.compressMinBytes {
+		if data, err = deflate(data); err != nil {
+			return fmt.Errorf("socket: compressing the packet: %w", err)
+		}
+		err = websocket.Message.Send(w.conn, data)
+	} else {
+		err = websocket.Message.Send(w.conn, string(data))
+	}
+	if err != nil {
 		return fmt.Errorf("socket: client unreachable when sending with error: %w", err)
 	}
 	return nil
 }
 
+// RemoteAddr returns the remote address of the HTTP request, which upgraded the connection
+func (w *WebSocketAdapter) RemoteAddr() string {
+	return w.conn.Request().RemoteAddr
+}
+
 func (w *WebSocketAdapter) Close() error {
 	if err := w.conn.Close(); err != nil {
 		return fmt.Errorf("socket: closing with error: %s", err.Error())
@@ -0,0 +1,225 @@
+package socket
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var ErrSocketWriterStopped = errors.New("socket: writer is stopped")
+
+// CapabilityBatch is the capability of coalescing multiple packets into a single "batch" packet
+const CapabilityBatch = "batch"
+
+// writer sends the packets queued by the socket on a single goroutine. When batching was negotiated,
+// then the queued packets are coalesced into a single "batch" packet
+type writer struct {
+	adapter Adapter
+	logger  *slog.Logger
+
+	batch         bool
+	batchSize     int
+	batchInterval time.Duration
+
+	queue chan Packet
+
+	// Only written by the writer's goroutine before closing the failed channel
+	err      error
+	failedCh chan empty
+
+	stopOnce sync.Once
+	stopCh   chan empty
+	doneCh   chan empty
+}
+
+func newWriter(s *Socket) *writer {
+	w := &writer{
+		adapter: s.adapter,
+		logger:  s.logger,
+
+		batch:         s.protocol.HasCapability(CapabilityBatch) && s.cfg.BatchMaxPackets > 1,
+		batchSize:     s.cfg.BatchMaxPackets,
+		batchInterval: s.cfg.BatchFlushInterval,
+
+		queue: make(chan Packet, s.cfg.WriteQueueSize),
+
+		failedCh: make(chan empty),
+
+		stopCh: make(chan empty),
+		doneCh: make(chan empty),
+	}
+	go w.start()
+	return w
+}
+
+func (w *writer) start() {
+	defer close(w.doneCh)
+
+	for {
+		select {
+		case pkt := <-w.queue:
+			w.write(pkt)
+		case <-w.stopCh:
+			// Flush the queued packets
+			for {
+				select {
+				case pkt := <-w.queue:
+					w.write(pkt)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// write sends the packet, which includes the queued packets when batching
+func (w *writer) write(pkt Packet) {
+	if !w.batch {
+		w.send(pkt)
+		return
+	}
+
+	pkts := []Packet{pkt}
+	pkts = w.collect(pkts)
+	if len(pkts) == 1 {
+		w.send(pkt)
+		return
+	}
+
+	batchSizeHist.Observe(float64(len(pkts)))
+	w.send(Packet{
+		Type: "batch",
+		Data: map[string]any{
+			"packets": pkts,
+		},
+	})
+}
+
+// collect adds the queued packets until the batch is full. When the flush interval is zero, then only the
+// packets already queued are added, otherwise it waits for more packets until the interval has elapsed
+func (w *writer) collect(pkts []Packet) []Packet {
+	var timeoutCh <-chan time.Time
+	if w.batchInterval > 0 {
+		timer := time.NewTimer(w.batchInterval)
+		defer timer.Stop()
+
+		timeoutCh = timer.C
+	}
+
+	for len(pkts) < w.batchSize {
+		if timeoutCh == nil {
+			select {
+			case pkt := <-w.queue:
+				pkts = append(pkts, pkt)
+			default:
+				return pkts
+			}
+			continue
+		}
+
+		select {
+		case pkt := <-w.queue:
+			pkts = append(pkts, pkt)
+		case <-timeoutCh:
+			return pkts
+		case <-w.stopCh:
+			return pkts
+		}
+	}
+	return pkts
+}
+
+func (w *writer) send(pkt Packet) {
+	// Drop the packets after the first error, as the client is unreachable
+	if w.err != nil {
+		return
+	}
+	if err := w.adapter.Send(pkt); err != nil {
+		w.err = err
+		close(w.failedCh)
+		w.logger.Warn("unable to send the packet", slog.String("type", pkt.Type), slog.Any("error", err))
+	}
+}
+
+// enqueue queues the packet, which blocks when the queue is full until the context is done. When sending
+// a previous packet failed, then its error is returned
+func (w *writer) enqueue(ctx context.Context, pkt Packet) error {
+	if err := w.checkState(); err != nil {
+		return err
+	}
+
+	// Queue the packet when the queue isn't full, even when the context is done
+	select {
+	case w.queue <- pkt:
+		return nil
+	default:
+	}
+
+	select {
+	case w.queue <- pkt:
+		return nil
+	case <-w.failedCh:
+		return w.err
+	case <-w.doneCh:
+		return ErrSocketWriterStopped
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// tryEnqueue queues the packet only when the queue isn't full, and returns false when it wasn't queued
+func (w *writer) tryEnqueue(pkt Packet) (bool, error) {
+	if err := w.checkState(); err != nil {
+		return false, err
+	}
+
+	select {
+	case w.queue <- pkt:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// checkState returns the error of the failed send, or an error when the writer is stopped
+func (w *writer) checkState() error {
+	select {
+	case <-w.failedCh:
+		return w.err
+	case <-w.doneCh:
+		return ErrSocketWriterStopped
+	default:
+		return nil
+	}
+}
+
+// stop sends the queued packets and waits for the writer to stop. When the packets aren't sent before
+// the timeout, then false is returned, where the writer is still sending. When the timeout is zero,
+// then it waits until the packets are sent
+func (w *writer) stop(timeout time.Duration) bool {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+	})
+	if timeout <= 0 {
+		<-w.doneCh
+		return true
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-w.doneCh:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// wait waits for the writer to stop
+func (w *writer) wait() {
+	<-w.doneCh
+}
@@ -0,0 +1,174 @@
+package socket
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_SocketWriterBatch(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.WriteQueueSize = 8
+	cfg.BatchMaxPackets = 2
+	cfg.Capabilities = []string{CapabilityBatch}
+
+	// The writer is blocked until a packet is read
+	a := newTestAdapter()
+	a.sent = make(chan Packet)
+
+	s, err := newSocket(a, cfg, Protocol{Version: 1, Capabilities: []string{CapabilityBatch}}, Handshake{})
+	testhelpers.AssertNoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		done <- s.onConnect()
+	}()
+
+	// Queue the events while the writer is blocked sending the "connect" packet
+	for !s.Connected() || len(s.writer.queue) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	testhelpers.AssertNoError(t, s.Emit("message", "1"))
+	testhelpers.AssertNoError(t, s.Emit("message", "2"))
+	testhelpers.AssertNoError(t, s.Emit("message", "3"))
+	testhelpers.AssertEqual(t, (<-a.sent).Type, "connect")
+
+	pkt := <-a.sent
+	testhelpers.AssertEqual(t, pkt.Type, "batch")
+
+	pkts := pkt.Data["packets"].([]Packet)
+	testhelpers.AssertEqual(t, len(pkts), 2)
+	testhelpers.AssertEqual(t, pkts[0].Data["args"], any([]any{"1"}))
+	testhelpers.AssertEqual(t, pkts[1].Data["args"], any([]any{"2"}))
+
+	// A single queued packet isn't batched
+	pkt = <-a.sent
+	testhelpers.AssertEqual(t, pkt.Type, "event")
+	testhelpers.AssertEqual(t, pkt.Data["args"], any([]any{"3"}))
+
+	// The queued packets are sent before disconnecting
+	go a.Close()
+	testhelpers.AssertNoError(t, <-done)
+}
+
+// failingAdapter fails sending the packets, once failing is set
+type failingAdapter struct {
+	*testAdapter
+	failing atomic.Bool
+}
+
+func (a *failingAdapter) Send(pkt Packet) error {
+	if a.failing.Load() {
+		return errors.New("send failed")
+	}
+	return a.testAdapter.Send(pkt)
+}
+
+func Test_SocketWriterError(t *testing.T) {
+	a := &failingAdapter{
+		testAdapter: newTestAdapter(),
+	}
+
+	s, err := newSocket(a, NewSocketConfig(), Protocol{Version: 1}, Handshake{})
+	testhelpers.AssertNoError(t, err)
+
+	done := connectTestSocket(t, s, a.testAdapter)
+
+	// The error of the queued packet which failed to be sent is returned by the following emits
+	a.failing.Store(true)
+	for s.Emit("message", "1") == nil {
+		time.Sleep(time.Millisecond)
+	}
+	testhelpers.AssertError(t, s.Emit("message", "2"))
+
+	go a.Close()
+	testhelpers.AssertNoError(t, <-done)
+}
+
+// stalledAdapter fails receiving once the error is sent, where the sent packets are no longer read
+type stalledAdapter struct {
+	*testAdapter
+	receiveErrCh chan error
+}
+
+func (a *stalledAdapter) Receive() (Packet, error) {
+	select {
+	case err := <-a.receiveErrCh:
+		return Packet{}, err
+	case <-a.closedCh:
+		return Packet{}, ErrClientUnreachable
+	}
+}
+
+func Test_SocketWriterFlushTimeout(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.WriteFlushTimeout = 10 * time.Millisecond
+
+	a := &stalledAdapter{
+		testAdapter:  newTestAdapter(),
+		receiveErrCh: make(chan error),
+	}
+	a.sent = make(chan Packet)
+
+	s, err := newSocket(a, cfg, Protocol{Version: 1}, Handshake{})
+	testhelpers.AssertNoError(t, err)
+
+	done := connectTestSocket(t, s, a.testAdapter)
+	for !s.Connected() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The client stopped reading, so the connection is closed once the flush timeout has elapsed
+	testhelpers.AssertNoError(t, s.Emit("message", "1"))
+	a.receiveErrCh <- ErrClientUnreachable
+	testhelpers.AssertNoError(t, <-done)
+
+	select {
+	case <-a.closedCh:
+	default:
+		t.Fatal("expected the adapter to be closed")
+	}
+}
+
+func Test_SocketWriterQueueFullDisconnect(t *testing.T) {
+	cfg := NewSocketConfig()
+	cfg.AsyncDispatch = true
+	cfg.WriteFlushTimeout = 10 * time.Millisecond
+	cfg.RateLimit = &RateLimit{Rate: 0, Burst: 1}
+	cfg.RateLimitPolicy = RateLimitDisconnect
+	cfg.RateLimitMaxViolations = 1
+
+	s, a := newTestSocket(t, cfg)
+	a.sent = make(chan Packet)
+
+	startedCh := make(chan struct{})
+	emitErrCh := make(chan error, 1)
+	s.On("flood", func(_ ...any) {
+		close(startedCh)
+		for i := range 1000 {
+			if err := s.Emit("message", i); err != nil {
+				emitErrCh <- err
+				return
+			}
+		}
+		emitErrCh <- nil
+	})
+
+	done := connectTestSocket(t, s, a)
+
+	// The client isn't reading, so the handler is blocked on the full queue when disconnected
+	a.receiveEvent("flood", 0)
+	<-startedCh
+	a.receiveEvent("flood", 0)
+
+	select {
+	case err := <-done:
+		testhelpers.AssertNoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the socket to disconnect")
+	}
+	testhelpers.AssertEqual(t, errors.Is(<-emitErrCh, ErrRateLimited), true)
+}
@@ -0,0 +1,499 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	opAppend = "append"
+	opDelete = "delete"
+)
+
+type record struct {
+	Op     string        `json:"op"`
+	Msg    *Message      `json:"msg,omitempty"`
+	Delete *deleteRecord `json:"delete,omitempty"`
+}
+
+type deleteRecord struct {
+	Room string    `json:"room"`
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type indexEntry struct {
+	time   time.Time
+	offset int64
+	size   int64
+}
+
+// logIndex is the in-memory index of each room's messages in the log file
+type logIndex struct {
+	// Messages of each room ordered by time
+	rooms map[string][]indexEntry
+
+	// Size of the records of the messages which haven't been deleted
+	live int64
+}
+
+func newLogIndex() *logIndex {
+	return &logIndex{
+		rooms: map[string][]indexEntry{},
+		live:  0,
+	}
+}
+
+func (idx *logIndex) apply(rec record, offset, size int64) {
+	switch rec.Op {
+	case opAppend:
+		if rec.Msg == nil {
+			return
+		}
+
+		entries := idx.rooms[rec.Msg.Room]
+		i, _ := slices.BinarySearchFunc(entries, rec.Msg.Time, func(e indexEntry, t time.Time) int {
+			// Insert after the entries with the same time, so the insertion order is kept
+			if e.time.After(t) {
+				return 1
+			}
+			return -1
+		})
+		idx.rooms[rec.Msg.Room] = slices.Insert(entries, i, indexEntry{
+			time:   rec.Msg.Time,
+			offset: offset,
+			size:   size,
+		})
+		idx.live += size
+	case opDelete:
+		if rec.Delete == nil {
+			return
+		}
+
+		d := rec.Delete
+		idx.rooms[d.Room] = slices.DeleteFunc(idx.rooms[d.Room], func(e indexEntry) bool {
+			if !inRange(e.time, d.From, d.To) {
+				return false
+			}
+			idx.live -= e.size
+			return true
+		})
+		if len(idx.rooms[d.Room]) == 0 {
+			delete(idx.rooms, d.Room)
+		}
+	}
+}
+
+// SyncPolicy defines when the records written to the log file are synced to the disk
+type SyncPolicy int
+
+const (
+	// SyncInterval syncs the log file periodically, where the records written since the last sync are lost
+	// when the machine crashes
+	SyncInterval SyncPolicy = iota
+
+	// SyncAlways syncs the log file after each record is written
+	SyncAlways
+
+	// SyncNever leaves syncing the log file to the operating system
+	SyncNever
+)
+
+// FileConfig defines the configuration settings for the file message store
+type FileConfig struct {
+	// When the records written to the log file are synced to the disk. Default is SyncInterval
+	SyncPolicy SyncPolicy
+
+	// How often the log file is synced when using SyncInterval and the retention is applied. Default is 1s
+	SyncInterval time.Duration
+
+	// Maximum age of the persisted messages, where the older messages are deleted periodically.
+	// Default is 0 i.e. messages are kept until deleted
+	Retention time.Duration
+
+	// Ratio of the log file taken up by deleted messages, before the log file is compacted automatically
+	// when messages are deleted. Default is 0.5
+	CompactRatio float64
+
+	// Minimum size of the log file in bytes, before the log file is compacted automatically. Default is 1MiB
+	CompactMinSize int64
+}
+
+// NewFileConfig initializes a file message store configuration instance with reasonable defaults
+func NewFileConfig() *FileConfig {
+	return &FileConfig{
+		SyncPolicy:     SyncInterval,
+		SyncInterval:   time.Second,
+		Retention:      0,
+		CompactRatio:   0.5,
+		CompactMinSize: 1 << 20,
+	}
+}
+
+// File is an embedded message store, which persists the messages to an append-only log file of JSON records.
+// An in-memory index of each room's messages is rebuilt from the log when the file is opened
+type File struct {
+	path string
+	cfg  *FileConfig
+	f    *os.File
+	size int64
+
+	// Whether records have been written since the last sync
+	dirty bool
+
+	index  *logIndex
+	closed bool
+	mu     sync.RWMutex
+
+	closeCh chan empty
+	doneCh  chan empty
+}
+
+type empty struct{}
+
+// OpenFile opens the log file at the path, which is created when it doesn't exist. When the configuration is nil,
+// then the default configuration is used
+func OpenFile(path string, cfg *FileConfig) (*File, error) {
+	if cfg == nil {
+		cfg = NewFileConfig()
+	}
+
+	f, size, index, err := openLog(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &File{
+		path:   path,
+		cfg:    cfg,
+		f:      f,
+		size:   size,
+		dirty:  false,
+		index:  index,
+		closed: false,
+
+		closeCh: make(chan empty),
+		doneCh:  make(chan empty),
+	}
+	go fs.run()
+
+	return fs, nil
+}
+
+// openLog opens the log file at the path and rebuilds its index
+func openLog(path string) (*os.File, int64, *logIndex, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("store: opening file %s: %w", path, err)
+	}
+
+	index := newLogIndex()
+	size, err := load(f, index)
+	if err != nil {
+		f.Close()
+		return nil, 0, nil, err
+	}
+
+	// Remove a partially written record e.g. when the process crashed whilst appending
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return nil, 0, nil, fmt.Errorf("store: truncating file %s: %w", path, err)
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return nil, 0, nil, fmt.Errorf("store: seeking file %s: %w", path, err)
+	}
+	return f, size, index, nil
+}
+
+// load rebuilds the index from the log, returning the size of the valid records
+func load(f *os.File, index *logIndex) (int64, error) {
+	r := bufio.NewReader(f)
+
+	var offset int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return offset, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("store: reading file %s: %w", f.Name(), err)
+		}
+
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return 0, fmt.Errorf("store: decoding record at offset %d of file %s: %w", offset, f.Name(), err)
+		}
+		index.apply(rec, offset, int64(len(line)))
+		offset += int64(len(line))
+	}
+}
+
+// run syncs the log file and applies the retention periodically, until the store is closed
+func (fs *File) run() {
+	defer close(fs.doneCh)
+
+	if fs.cfg.SyncInterval <= 0 {
+		<-fs.closeCh
+		return
+	}
+
+	ticker := time.NewTicker(fs.cfg.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fs.mu.Lock()
+			if !fs.closed {
+				// Ignore the errors, as the retention and sync are retried on the next tick and
+				// the sync when closing
+				fs.retain()
+				if fs.cfg.SyncPolicy == SyncInterval {
+					fs.sync()
+				}
+			}
+			fs.mu.Unlock()
+		case <-fs.closeCh:
+			return
+		}
+	}
+}
+
+// retain deletes the messages older than the retention. The lock must be held by the caller
+func (fs *File) retain() error {
+	if fs.cfg.Retention <= 0 {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-fs.cfg.Retention)
+	for room, entries := range fs.index.rooms {
+		if len(entries) == 0 || !entries[0].time.Before(cutoff) {
+			continue
+		}
+		if err := fs.delete(room, time.Time{}, cutoff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sync syncs the log file when records have been written since the last sync. The lock must be held by the caller
+func (fs *File) sync() error {
+	if !fs.dirty {
+		return nil
+	}
+	if err := fs.f.Sync(); err != nil {
+		return fmt.Errorf("store: syncing file %s: %w", fs.path, err)
+	}
+	fs.dirty = false
+	return nil
+}
+
+func (fs *File) write(rec record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("store: encoding record: %w", err)
+	}
+	b = append(b, '\n')
+
+	if _, err := fs.f.Write(b); err != nil {
+		return fmt.Errorf("store: writing record to file %s: %w", fs.path, err)
+	}
+
+	fs.index.apply(rec, fs.size, int64(len(b)))
+	fs.size += int64(len(b))
+	fs.dirty = true
+
+	if fs.cfg.SyncPolicy == SyncAlways {
+		return fs.sync()
+	}
+	return nil
+}
+
+func (fs *File) Append(msg Message) error {
+	if msg.Room == "" {
+		return ErrMessageRoomNone
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.closed {
+		return ErrStoreClosed
+	}
+	return fs.write(record{
+		Op:  opAppend,
+		Msg: &msg,
+	})
+}
+
+func (fs *File) Range(room string, from, to time.Time, limit int) ([]Message, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	if fs.closed {
+		return nil, ErrStoreClosed
+	}
+
+	var entries []indexEntry
+	for _, e := range fs.index.rooms[room] {
+		if inRange(e.time, from, to) {
+			entries = append(entries, e)
+		}
+	}
+	entries = applyLimit(entries, limit)
+
+	msgs := make([]Message, 0, len(entries))
+	for _, e := range entries {
+		msg, err := fs.read(e)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+func (fs *File) read(e indexEntry) (Message, error) {
+	b := make([]byte, e.size)
+	if _, err := fs.f.ReadAt(b, e.offset); err != nil {
+		return Message{}, fmt.Errorf("store: reading record at offset %d of file %s: %w", e.offset, fs.path, err)
+	}
+
+	var rec record
+	if err := json.Unmarshal(bytes.TrimSpace(b), &rec); err != nil || rec.Msg == nil {
+		return Message{}, fmt.Errorf("store: decoding record at offset %d of file %s", e.offset, fs.path)
+	}
+	return *rec.Msg, nil
+}
+
+func (fs *File) Delete(room string, from, to time.Time) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.closed {
+		return ErrStoreClosed
+	}
+	return fs.delete(room, from, to)
+}
+
+// delete writes the delete record, compacting the log file when mostly taken up by deleted messages.
+// The lock must be held by the caller
+func (fs *File) delete(room string, from, to time.Time) error {
+	err := fs.write(record{
+		Op: opDelete,
+		Delete: &deleteRecord{
+			Room: room,
+			From: from,
+			To:   to,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	dead := fs.size - fs.index.live
+	if fs.size < fs.cfg.CompactMinSize || float64(dead) < float64(fs.size)*fs.cfg.CompactRatio {
+		return nil
+	}
+	return fs.compact()
+}
+
+// Compact rewrites the log file with only the messages which haven't been deleted
+func (fs *File) Compact() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.closed {
+		return ErrStoreClosed
+	}
+	return fs.compact()
+}
+
+// compact rewrites the log file. The compacted file is opened before replacing the log file, so the store
+// keeps using the current log file when compacting fails. The lock must be held by the caller
+func (fs *File) compact() error {
+	tmpPath := fs.path + ".compact"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("store: creating file %s: %w", tmpPath, err)
+	}
+	defer os.Remove(tmpPath)
+
+	w := bufio.NewWriter(tmp)
+	for _, entries := range fs.index.rooms {
+		for _, e := range entries {
+			b := make([]byte, e.size)
+			if _, err := fs.f.ReadAt(b, e.offset); err != nil {
+				tmp.Close()
+				return fmt.Errorf("store: reading record at offset %d of file %s: %w", e.offset, fs.path, err)
+			}
+			w.Write(b)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("store: writing file %s: %w", tmpPath, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("store: syncing file %s: %w", tmpPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("store: closing file %s: %w", tmpPath, err)
+	}
+
+	f, size, index, err := openLog(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, fs.path); err != nil {
+		f.Close()
+		return fmt.Errorf("store: renaming file %s: %w", tmpPath, err)
+	}
+
+	// The compacted file has replaced the log file, so the previous log file is no longer used
+	prev := fs.f
+	fs.f = f
+	fs.size = size
+	fs.index = index
+	fs.dirty = false
+
+	if err := prev.Close(); err != nil {
+		return fmt.Errorf("store: closing file %s: %w", fs.path, err)
+	}
+	return nil
+}
+
+func (fs *File) Close() error {
+	fs.mu.Lock()
+	if fs.closed {
+		fs.mu.Unlock()
+		return ErrStoreClosed
+	}
+	fs.closed = true
+	fs.mu.Unlock()
+
+	close(fs.closeCh)
+	<-fs.doneCh
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	clear(fs.index.rooms)
+
+	err := fs.sync()
+	if closeErr := fs.f.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("store: closing file %s: %w", fs.path, closeErr)
+	}
+	return err
+}
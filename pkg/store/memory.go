@@ -0,0 +1,78 @@
+package store
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Memory is an in-memory message store, which is useful for testing
+type Memory struct {
+	rooms  map[string][]Message
+	closed bool
+	mu     sync.RWMutex
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		rooms:  map[string][]Message{},
+		closed: false,
+	}
+}
+
+func (m *Memory) Append(msg Message) error {
+	if msg.Room == "" {
+		return ErrMessageRoomNone
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrStoreClosed
+	}
+	m.rooms[msg.Room] = append(m.rooms[msg.Room], msg)
+	return nil
+}
+
+func (m *Memory) Range(room string, from, to time.Time, limit int) ([]Message, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrStoreClosed
+	}
+
+	var msgs []Message
+	for _, msg := range m.rooms[room] {
+		if inRange(msg.Time, from, to) {
+			msgs = append(msgs, msg)
+		}
+	}
+	return slices.Clone(applyLimit(msgs, limit)), nil
+}
+
+func (m *Memory) Delete(room string, from, to time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrStoreClosed
+	}
+	m.rooms[room] = slices.DeleteFunc(m.rooms[room], func(msg Message) bool {
+		return inRange(msg.Time, from, to)
+	})
+	return nil
+}
+
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrStoreClosed
+	}
+	m.closed = true
+	clear(m.rooms)
+	return nil
+}
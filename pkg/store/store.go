@@ -0,0 +1,55 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrStoreClosed     = errors.New("store: store is closed")
+	ErrMessageRoomNone = errors.New("store: message room cannot be empty")
+)
+
+// Message is a message persisted for a room, where the data is encoded as JSON
+type Message struct {
+	ID       string          `json:"id"`
+	Room     string          `json:"room"`
+	SenderID string          `json:"senderId"`
+	Data     json.RawMessage `json:"data"`
+	Topics   []string        `json:"topics,omitempty"`
+	Time     time.Time       `json:"time"`
+}
+
+// MessageStore defines the persistent storage of room messages.
+// For time ranges, the from time is inclusive and the to time is exclusive, where a zero time is unbounded
+type MessageStore interface {
+	// Append persists the message
+	Append(msg Message) error
+
+	// Range returns the messages of the room within the time range in chronological order.
+	// When the limit is greater than zero, then only the most recent messages up to the limit are returned
+	Range(room string, from, to time.Time, limit int) ([]Message, error)
+
+	// Delete removes the messages of the room within the time range
+	Delete(room string, from, to time.Time) error
+
+	Close() error
+}
+
+func inRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && !t.Before(to) {
+		return false
+	}
+	return true
+}
+
+func applyLimit[T any](s []T, limit int) []T {
+	if limit > 0 && len(s) > limit {
+		return s[len(s)-limit:]
+	}
+	return s
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.log")
+
+	fs, err := OpenFile(path, nil)
+	testhelpers.AssertNoError(t, err)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for i := range 5 {
+		err := fs.Append(Message{
+			ID:   fmt.Sprint(i),
+			Room: "root",
+			Data: json.RawMessage(fmt.Sprintf(`"msg %d"`, i)),
+			Time: now.Add(time.Duration(i) * time.Second),
+		})
+		testhelpers.AssertNoError(t, err)
+	}
+	testhelpers.AssertNoError(t, fs.Append(Message{ID: "other", Room: "other", Data: json.RawMessage(`1`), Time: now}))
+	testhelpers.AssertError(t, fs.Append(Message{ID: "none"}))
+
+	ids := func(msgs []Message, err error) []string {
+		t.Helper()
+		testhelpers.AssertNoError(t, err)
+
+		var res []string
+		for _, msg := range msgs {
+			res = append(res, msg.ID)
+		}
+		return res
+	}
+
+	testhelpers.AssertEqual(t, ids(fs.Range("root", time.Time{}, time.Time{}, 0)), []string{"0", "1", "2", "3", "4"})
+	testhelpers.AssertEqual(t, ids(fs.Range("root", now.Add(time.Second), now.Add(3*time.Second), 0)), []string{"1", "2"})
+	testhelpers.AssertEqual(t, ids(fs.Range("root", time.Time{}, time.Time{}, 2)), []string{"3", "4"})
+
+	testhelpers.AssertNoError(t, fs.Delete("root", time.Time{}, now.Add(2*time.Second)))
+	testhelpers.AssertEqual(t, ids(fs.Range("root", time.Time{}, time.Time{}, 0)), []string{"2", "3", "4"})
+	testhelpers.AssertNoError(t, fs.Close())
+
+	// Simulate a partially written record
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	testhelpers.AssertNoError(t, err)
+	_, err = f.WriteString(`{"op":"append","msg":{"id":"partial"`)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertNoError(t, f.Close())
+
+	// The index is rebuilt from the log when reopened
+	fs, err = OpenFile(path, nil)
+	testhelpers.AssertNoError(t, err)
+	defer fs.Close()
+
+	msgs, err := fs.Range("root", time.Time{}, time.Time{}, 0)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, len(msgs), 3)
+	testhelpers.AssertEqual(t, msgs[0].ID, "2")
+	testhelpers.AssertEqual(t, string(msgs[0].Data), `"msg 2"`)
+	testhelpers.AssertEqual(t, msgs[0].Time.Equal(now.Add(2*time.Second)), true)
+
+	testhelpers.AssertNoError(t, fs.Compact())
+	testhelpers.AssertEqual(t, ids(fs.Range("root", time.Time{}, time.Time{}, 0)), []string{"2", "3", "4"})
+	testhelpers.AssertEqual(t, ids(fs.Range("other", time.Time{}, time.Time{}, 0)), []string{"other"})
+}
+
+func Test_FileCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.log")
+
+	cfg := NewFileConfig()
+	cfg.SyncPolicy = SyncAlways
+	cfg.CompactMinSize = 0
+
+	fs, err := OpenFile(path, cfg)
+	testhelpers.AssertNoError(t, err)
+	defer fs.Close()
+
+	now := time.Now().UTC()
+	for i := range 4 {
+		err := fs.Append(Message{
+			ID:   fmt.Sprint(i),
+			Room: "root",
+			Data: json.RawMessage(`""`),
+			Time: now.Add(time.Duration(i) * time.Second),
+		})
+		testhelpers.AssertNoError(t, err)
+	}
+
+	before, err := os.Stat(path)
+	testhelpers.AssertNoError(t, err)
+
+	// Most of the log file is taken up by deleted messages, so it's compacted automatically
+	testhelpers.AssertNoError(t, fs.Delete("root", time.Time{}, now.Add(3*time.Second)))
+
+	after, err := os.Stat(path)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, after.Size() < before.Size(), true)
+
+	msgs, err := fs.Range("root", time.Time{}, time.Time{}, 0)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, len(msgs), 1)
+	testhelpers.AssertEqual(t, msgs[0].ID, "3")
+
+	// The compacted log file is used for appending
+	testhelpers.AssertNoError(t, fs.Append(Message{ID: "4", Room: "root", Data: json.RawMessage(`""`), Time: now.Add(4 * time.Second)}))
+	msgs, err = fs.Range("root", time.Time{}, time.Time{}, 0)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, len(msgs), 2)
+}
+
+func Test_FileRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.log")
+
+	cfg := NewFileConfig()
+	cfg.SyncInterval = 10 * time.Millisecond
+	cfg.Retention = time.Minute
+
+	fs, err := OpenFile(path, cfg)
+	testhelpers.AssertNoError(t, err)
+	defer fs.Close()
+
+	now := time.Now().UTC()
+	testhelpers.AssertNoError(t, fs.Append(Message{ID: "old", Room: "root", Data: json.RawMessage(`""`), Time: now.Add(-2 * time.Minute)}))
+	testhelpers.AssertNoError(t, fs.Append(Message{ID: "new", Room: "root", Data: json.RawMessage(`""`), Time: now}))
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		msgs, err := fs.Range("root", time.Time{}, time.Time{}, 0)
+		testhelpers.AssertNoError(t, err)
+		if len(msgs) == 1 {
+			testhelpers.AssertEqual(t, msgs[0].ID, "new")
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for the retention to be applied")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
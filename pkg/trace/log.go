@@ -0,0 +1,36 @@
+package trace
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogExporter logs the exported spans at the debug level
+type LogExporter struct {
+	logger *slog.Logger
+}
+
+func NewLogExporter(logger *slog.Logger) *LogExporter {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &LogExporter{
+		logger: logger,
+	}
+}
+
+func (e *LogExporter) ExportSpan(span SpanData) {
+	attrs := []slog.Attr{
+		slog.String("trace_id", span.TraceID),
+		slog.String("span_id", span.SpanID),
+		slog.String("parent_span_id", span.ParentSpanID),
+		slog.Duration("duration", span.End.Sub(span.Start)),
+	}
+	for k, v := range span.Attributes {
+		attrs = append(attrs, slog.Any(k, v))
+	}
+	if span.Err != nil {
+		attrs = append(attrs, slog.Any("error", span.Err))
+	}
+	e.logger.LogAttrs(context.Background(), slog.LevelDebug, span.Name, attrs...)
+}
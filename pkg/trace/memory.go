@@ -0,0 +1,40 @@
+package trace
+
+import (
+	"slices"
+	"sync"
+)
+
+// InMemoryExporter keeps the exported spans in memory, which is useful for testing
+type InMemoryExporter struct {
+	spans []SpanData
+	mu    sync.Mutex
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{
+		spans: nil,
+	}
+}
+
+func (e *InMemoryExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns the exported spans in the order they were ended
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return slices.Clone(e.spans)
+}
+
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = nil
+}
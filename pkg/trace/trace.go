@@ -0,0 +1,188 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"maps"
+	"sync"
+	"time"
+)
+
+// SpanContext identifies a span, which is propagated across process boundaries e.g. in a packet.
+// Idea based on URL: https://www.w3.org/TR/trace-context/
+type SpanContext struct {
+	TraceID string `json:"traceId"`
+	SpanID  string `json:"spanId"`
+}
+
+// IsValid returns true when both the trace ID and span ID are valid hex encoded IDs
+func (sc SpanContext) IsValid() bool {
+	return isValidID(sc.TraceID, traceIDSize) && isValidID(sc.SpanID, spanIDSize)
+}
+
+// SpanData is the read-only data of an ended span, which is passed to the exporter
+type SpanData struct {
+	SpanContext
+	ParentSpanID string
+	Name         string
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]any
+	Err          error
+}
+
+// Exporter exports the ended spans e.g. to a tracing backend
+type Exporter interface {
+	ExportSpan(span SpanData)
+}
+
+// Tracer creates spans, which are exported when ended. A nil tracer is valid and creates no-op spans
+type Tracer struct {
+	exporter Exporter
+}
+
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{
+		exporter: exporter,
+	}
+}
+
+// Span is an operation within a trace. A nil span is valid and all its methods are no-ops
+type Span struct {
+	tracer *Tracer
+	data   SpanData
+	ended  bool
+	mu     sync.Mutex
+}
+
+type spanContextKey struct{}
+
+// Start starts a span, which is a child of the span or remote span context in the context.
+// When there is no parent, then a new trace is started
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	parent := SpanContextFromContext(ctx)
+	traceID := parent.TraceID
+	if !parent.IsValid() {
+		traceID = newID(traceIDSize)
+		parent = SpanContext{}
+	}
+
+	s := &Span{
+		tracer: t,
+		data: SpanData{
+			SpanContext: SpanContext{
+				TraceID: traceID,
+				SpanID:  newID(spanIDSize),
+			},
+			ParentSpanID: parent.SpanID,
+			Name:         name,
+			Start:        time.Now(),
+			Attributes:   map[string]any{},
+		},
+	}
+	return context.WithValue(ctx, spanContextKey{}, s.data.SpanContext), s
+}
+
+// ContextWithRemoteSpanContext returns a context with the span context received from another process,
+// so the spans started with the context are its children
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	if !sc.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context of the context, which is empty when there isn't one
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Attributes[key] = value
+}
+
+func (s *Span) SetError(err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Err = err
+}
+
+// End ends the span and exports it. Only the first call has an effect
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+
+	data := s.data
+	data.Attributes = maps.Clone(s.data.Attributes)
+	s.mu.Unlock()
+
+	if s.tracer.exporter != nil {
+		s.tracer.exporter.ExportSpan(data)
+	}
+}
+
+const (
+	traceIDSize = 16
+	spanIDSize  = 8
+)
+
+func newID(size int) string {
+	b := make([]byte, size)
+
+	// Ignore the error, as a span ID is not security sensitive
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func isValidID(id string, size int) bool {
+	if len(id) != size*2 {
+		return false
+	}
+
+	b, err := hex.DecodeString(id)
+	if err != nil {
+		return false
+	}
+
+	// An all zero ID is invalid
+	for _, v := range b {
+		if v != 0 {
+			return true
+		}
+	}
+	return false
+}
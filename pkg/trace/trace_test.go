@@ -0,0 +1,78 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	testhelpers "github.com/softwarespot/chatterbox/pkg/test-helpers"
+)
+
+func Test_Tracer(t *testing.T) {
+	exp := NewInMemoryExporter()
+	tracer := NewTracer(exp)
+
+	remote := SpanContext{
+		TraceID: "0af7651916cd43dd8448eb211c80319c",
+		SpanID:  "b7ad6b7169203331",
+	}
+	testhelpers.AssertEqual(t, remote.IsValid(), true)
+
+	ctx := ContextWithRemoteSpanContext(context.Background(), remote)
+	ctx, parent := tracer.Start(ctx, "parent")
+	_, child := tracer.Start(ctx, "child")
+
+	child.SetAttribute("key", "value")
+	child.SetError(errors.New("child error"))
+	child.End()
+	child.End()
+	parent.End()
+
+	spans := exp.Spans()
+	testhelpers.AssertEqual(t, len(spans), 2)
+
+	testhelpers.AssertEqual(t, spans[0].Name, "child")
+	testhelpers.AssertEqual(t, spans[0].TraceID, remote.TraceID)
+	testhelpers.AssertEqual(t, spans[0].ParentSpanID, parent.SpanContext().SpanID)
+	testhelpers.AssertEqual(t, spans[0].Attributes["key"], any("value"))
+	testhelpers.AssertEqual(t, spans[0].Err.Error(), "child error")
+
+	testhelpers.AssertEqual(t, spans[1].Name, "parent")
+	testhelpers.AssertEqual(t, spans[1].TraceID, remote.TraceID)
+	testhelpers.AssertEqual(t, spans[1].ParentSpanID, remote.SpanID)
+	testhelpers.AssertEqual(t, spans[1].SpanContext.IsValid(), true)
+
+	exp.Reset()
+	testhelpers.AssertEqual(t, len(exp.Spans()), 0)
+}
+
+func Test_TracerNewTrace(t *testing.T) {
+	exp := NewInMemoryExporter()
+	tracer := NewTracer(exp)
+
+	// An invalid remote span context is ignored
+	ctx := ContextWithRemoteSpanContext(context.Background(), SpanContext{TraceID: "invalid", SpanID: "invalid"})
+	ctx, span := tracer.Start(ctx, "root")
+	span.End()
+
+	testhelpers.AssertEqual(t, SpanContextFromContext(ctx), span.SpanContext())
+
+	spans := exp.Spans()
+	testhelpers.AssertEqual(t, len(spans), 1)
+	testhelpers.AssertEqual(t, spans[0].ParentSpanID, "")
+	testhelpers.AssertEqual(t, spans[0].SpanContext.IsValid(), true)
+}
+
+func Test_TracerNil(t *testing.T) {
+	var tracer *Tracer
+
+	ctx := context.Background()
+	gotCtx, span := tracer.Start(ctx, "noop")
+	testhelpers.AssertEqual(t, gotCtx, ctx)
+
+	// All methods of a nil span are no-ops
+	span.SetAttribute("key", "value")
+	span.SetError(errors.New("error"))
+	span.End()
+	testhelpers.AssertEqual(t, span.SpanContext().IsValid(), false)
+}
@@ -15,8 +15,18 @@ const msgBtnEl = document.getElementById('msg-btn');
 
 hideElement(leaveBtnEl);
 
+// The credentials are kept in the session storage instead of the page URL, so they don't leak into
+// the browser's history, the shared links or the server's logs
+const AUTH_STORAGE_KEY = 'auth';
+
+function getRoomPasswordStorageKey(roomName) {
+    return `password:${roomName}`;
+}
+
 const protocol = location.protocol === 'http:' ? 'ws' : 'wss';
-const socket = io(`${protocol}://${location.host}/chat`);
+const socket = io(`${protocol}://${location.host}/chat`, {
+    auth: sessionStorage.getItem(AUTH_STORAGE_KEY) ?? '',
+});
 roomNameEl.focus();
 
 const socketId = socket.id;
@@ -56,6 +66,18 @@ socket.on('connect', (id) => {
     }
 });
 
+socket.on('connect_error', (reason) => {
+    logMessage('System', `Connection rejected. Reason: ${reason}.`);
+
+    // Ask for the token and reconnect, as the token is likely missing or invalid
+    sessionStorage.removeItem(AUTH_STORAGE_KEY);
+    const token = prompt('Please enter the auth token.');
+    if (token !== null && token.trim() !== '') {
+        sessionStorage.setItem(AUTH_STORAGE_KEY, token.trim());
+        location.reload();
+    }
+});
+
 socket.on('disconnect', (reason) => {
     logMessage('System', `Disconnected socket. Reason: ${reason}.`);
 
@@ -74,7 +96,22 @@ function sendJoinRoom() {
         return;
     }
 
-    socket.emit('join', roomName, () => {
+    // The password is remembered for the session, so the room is automatically joined when reloading
+    const passwordStorageKey = getRoomPasswordStorageKey(roomName);
+    let password = sessionStorage.getItem(passwordStorageKey);
+    if (password === null) {
+        password = prompt(`Please enter the password of the room ${roomName}, or leave it empty for none.`) ?? '';
+        sessionStorage.setItem(passwordStorageKey, password);
+    }
+
+    socket.emit('join', roomName, password, (err) => {
+        if (err !== undefined) {
+            // Ask for the password again on the next join, as it's likely invalid
+            sessionStorage.removeItem(passwordStorageKey);
+            logMessage('System', `Unable to join the room ${roomName}. Reason: ${err.message}.`);
+            return;
+        }
+
         roomNameEl.disabled = true;
         hideElement(joinBtnEl);
         showElement(leaveBtnEl);
@@ -1,6 +1,72 @@
 export const LOG_LEVEL_ERROR = 1;
 export const LOG_LEVEL_DEBUG = 2;
 
+// Version of the wire protocol and the capabilities supported by this client, which are negotiated with the server
+export const PROTOCOL_VERSION = 1;
+export const CAPABILITIES = [
+    'ack-errors',
+    'batch',
+    'binary',
+
+    // The compressed packets are decompressed with the DecompressionStream API, which older browsers don't support
+    ...(typeof DecompressionStream === 'function' ? ['compression'] : []),
+];
+
+// Key of the object a binary arg is encoded as, when the binary capability was negotiated
+const BINARY_KEY = '$binary';
+
+// Create a new trace context, so the server's spans can be followed back to the emitted event.
+// Idea based on URL: https://www.w3.org/TR/trace-context/
+function newTraceContext() {
+    return {
+        traceId: randomHex(16),
+        spanId: randomHex(8),
+    };
+}
+
+function randomHex(size) {
+    const bytes = crypto.getRandomValues(new Uint8Array(size));
+    return Array.from(bytes, (b) => b.toString(16).padStart(2, '0')).join('');
+}
+
+// Encode the binary args, including those nested in arrays and objects, as {"$binary": "<base64>"} objects
+function encodeBinary(value) {
+    if (value instanceof ArrayBuffer || ArrayBuffer.isView(value)) {
+        const bytes = value instanceof ArrayBuffer ? new Uint8Array(value) : new Uint8Array(value.buffer, value.byteOffset, value.byteLength);
+        return {
+            [BINARY_KEY]: btoa(Array.from(bytes, (b) => String.fromCharCode(b)).join('')),
+        };
+    }
+    if (Array.isArray(value)) {
+        return value.map(encodeBinary);
+    }
+    if (value !== null && typeof value === 'object') {
+        return Object.fromEntries(Object.entries(value).map(([k, v]) => [k, encodeBinary(v)]));
+    }
+    return value;
+}
+
+// Decode the {"$binary": "<base64>"} objects, including those nested in arrays and objects, to a Uint8Array
+function decodeBinary(value) {
+    if (Array.isArray(value)) {
+        return value.map(decodeBinary);
+    }
+    if (value !== null && typeof value === 'object') {
+        const keys = Object.keys(value);
+        if (keys.length === 1 && keys[0] === BINARY_KEY && typeof value[BINARY_KEY] === 'string') {
+            return Uint8Array.from(atob(value[BINARY_KEY]), (c) => c.charCodeAt(0));
+        }
+        return Object.fromEntries(Object.entries(value).map(([k, v]) => [k, decodeBinary(v)]));
+    }
+    return value;
+}
+
+// Decompress a binary frame, which the server compressed with deflate
+async function inflate(buffer) {
+    const stream = new Blob([buffer]).stream().pipeThrough(new DecompressionStream('deflate-raw'));
+    return new Response(stream).text();
+}
+
 // Idea based on URL: https://github.com/socketio/socket.io/blob/main/examples/basic-websocket-client/src/index.js
 export class Socket {
     #subscribers = new Map();
@@ -54,49 +120,74 @@ export class Socket {
 
     #connected = false;
     #id = undefined;
+    #version = undefined;
+    #capabilities = [];
 
     #ackId = 0;
     #ackFns = new Map();
 
+    // The received frames are handled in order, as decompressing a binary frame is asynchronous
+    #received = Promise.resolve();
+
     #level = LOG_LEVEL_DEBUG;
 
-    constructor(url) {
+    #auth = undefined;
+
+    constructor(url, { auth } = {}) {
         this.#url = url;
+        this.#auth = auth;
         this.connect();
     }
 
     #onPacket(packet) {
         switch (packet.type) {
             case 'connect':
-                this.#onConnect(packet.data.id);
+                this.#onConnect(packet.data);
+                break;
+            case 'connect_error':
+                this.debug('Connection rejected:', packet.data.reason);
+                this.#emit('connect_error', packet.data.reason);
                 break;
             case 'disconnect':
                 this.#onDisconnect(packet.data.reason);
                 break;
+            case 'batch':
+                for (const batchPacket of packet.data.packets) {
+                    this.#onPacket(batchPacket);
+                }
+                break;
+            case 'error':
+                this.debug('Protocol error:', packet.data.reason);
+                this.#emit('protocol_error', packet.data.reason);
+                break;
             case 'ack':
                 if (this.#ackFns.has(packet.data.id)) {
                     const ackFn = this.#ackFns.get(packet.data.id);
-                    ackFn(...packet.data.args);
+                    ackFn(...this.#decodeArgs(packet.data.args));
                     this.#ackFns.delete(packet.data.id);
                 }
                 break;
-            case 'event':
+            case 'event': {
+                const args = this.#decodeArgs(packet.data.args);
                 if (packet.data.ackId > 0) {
-                    packet.data.args.push((...args) => {
-                        this.#emitAck(packet.data.ackId, ...args);
+                    args.push((...ackArgs) => {
+                        this.#emitAck(packet.data.ackId, ...ackArgs);
                     });
                 }
-                this.#emit(packet.data.event, ...packet.data.args);
+                this.#emit(packet.data.event, ...args);
                 break;
+            }
             default:
                 this.debug('Unknown packet type:', packet);
                 break;
         }
     }
 
-    #onConnect(id) {
+    #onConnect({ id, version = 1, capabilities = [] }) {
         this.#connected = true;
         this.#id = id;
+        this.#version = version;
+        this.#capabilities = capabilities;
 
         this.#emit('connect', id);
     }
@@ -106,9 +197,26 @@ export class Socket {
             return this;
         }
 
-        this.#ws = new WebSocket(this.#url);
+        const url = new URL(this.#url, window.location.href);
+        url.searchParams.set('protocol', PROTOCOL_VERSION);
+        url.searchParams.set('capabilities', CAPABILITIES.join(','));
+
+        this.#ws = new WebSocket(url);
+        this.#ws.binaryType = 'arraybuffer';
         this.#ws.onopen = (evt) => {
             this.debug('ONOPEN HANDLER', evt);
+
+            // The credentials are sent in the first packet, as the query string of the URL is likely logged
+            if (this.#auth) {
+                this.#ws.send(
+                    JSON.stringify({
+                        type: 'auth',
+                        data: {
+                            token: this.#auth,
+                        },
+                    }),
+                );
+            }
         };
         this.#ws.onerror = (evt) => {
             this.debug('ONERROR HANDLER', evt);
@@ -119,8 +227,14 @@ export class Socket {
         };
         this.#ws.onmessage = (evt) => {
             this.debug('ONMESSAGE HANDLER', evt);
-            const packet = JSON.parse(evt.data);
-            this.#onPacket(packet);
+            this.#received = this.#received
+                .then(async () => {
+                    const data = typeof evt.data === 'string' ? evt.data : await inflate(evt.data);
+                    this.#onPacket(JSON.parse(data));
+                })
+                .catch((err) => {
+                    this.debug('Unable to handle the frame:', err);
+                });
         };
         return this;
     }
@@ -132,6 +246,8 @@ export class Socket {
 
         this.#connected = false;
         this.#id = undefined;
+        this.#version = undefined;
+        this.#capabilities = [];
 
         this.#ws.onclose;
         this.#ws.close();
@@ -156,6 +272,14 @@ export class Socket {
         return this.#id;
     }
 
+    get version() {
+        return this.#version;
+    }
+
+    hasCapability(capability) {
+        return this.#capabilities.includes(capability);
+    }
+
     get connected() {
         return this.#connected;
     }
@@ -183,9 +307,10 @@ export class Socket {
             type: 'event',
             data: {
                 event,
-                args: args,
+                args: this.#encodeArgs(args),
                 ackId: hasAckFn ? this.#ackId : 0,
             },
+            trace: newTraceContext(),
         });
         this.#ws.send(packet);
         return true;
@@ -196,12 +321,20 @@ export class Socket {
             type: 'ack',
             data: {
                 id: id,
-                args: args,
+                args: this.#encodeArgs(args),
             },
         });
         this.#ws.send(packet);
     }
 
+    #encodeArgs(args) {
+        return this.hasCapability('binary') ? args.map(encodeBinary) : args;
+    }
+
+    #decodeArgs(args) {
+        return this.hasCapability('binary') ? args.map(decodeBinary) : args;
+    }
+
     on(event, fn) {
         this.#on(event, fn);
         return this;
@@ -223,7 +356,7 @@ export class Socket {
     }
 }
 
-export function io(url) {
-    const socket = new Socket(url);
+export function io(url, opts) {
+    const socket = new Socket(url, opts);
     return socket;
 }